@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/app"
+	"github.com/jvitoroc/todo-go/model"
+)
+
+func (api *API) InitBatch() {
+	api.Router.Batch.Handle("", api.createProtectedHandler(api.Batch, true)).Methods("POST")
+}
+
+// Batch runs the operations in order, stopping at the first one that fails,
+// in which case none of them are applied.
+func (api *API) Batch(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	batch, err := model.BatchFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := batch.Validate(); err != nil {
+		return err
+	}
+
+	results := make([]model.BatchResult, 0, len(batch.Operations))
+	tempIds := map[string]int{}
+	err = api.App.Batch(func(app *app.App) *model.AppError {
+		for i := range batch.Operations {
+			res := runBatchOperation(app, &batch.Operations[i], tempIds, ctx.CurrentUser.ID)
+			results = append(results, model.BatchResult{Status: res.GetCode(), Body: res})
+
+			if err, failed := res.(*model.AppError); failed {
+				return model.NewBatchError(i, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err.AddObject("results", results)
+	}
+
+	res := model.NewOKResponse(model.MSG_BATCH_DONE)
+	res.AddObject("results", results)
+	res.AddObject("tempIds", tempIds)
+	return res
+}
+
+func runBatchOperation(app *app.App, op *model.BatchOperation, tempIds map[string]int, userId int) hn.Response {
+	switch op.Method {
+	case model.BATCH_CREATE:
+		todo, err := model.TodoFromJson(bytes.NewReader(op.Body))
+		if err != nil {
+			return err
+		}
+
+		if err := todo.Validate(); err != nil {
+			return err
+		}
+
+		if op.ParentTodoID != nil {
+			parentId, err := op.ParentTodoID.Resolve("parentTodoId", tempIds)
+			if err != nil {
+				return err
+			}
+			todo.ParentTodoID = &parentId
+		}
+
+		todo.UserID = userId
+
+		if err := app.CreateTodo(todo); err != nil {
+			return err
+		}
+
+		if op.TempID != "" {
+			tempIds[op.TempID] = todo.ID
+		}
+
+		return model.NewCreatedResponse(model.MSG_TODO_CREATED).AddObject("todo", todo)
+
+	case model.BATCH_UPDATE:
+		todo, err := model.UpdateTodoFromJson(bytes.NewReader(op.Body))
+		if err != nil {
+			return err
+		}
+
+		if err := todo.Validate(); err != nil {
+			return err
+		}
+
+		if todo.ID, err = op.TodoID.Resolve("todoId", tempIds); err != nil {
+			return err
+		}
+
+		// like PATCH /todo/{id}, changes must be made against a known version
+		if op.Version != nil {
+			todo.Version = op.Version
+		}
+		if todo.Version == nil {
+			return model.NewPreconditionRequiredError(model.MSG_TODO_VERSION_MISSING)
+		}
+
+		dbTodo, affected, err := app.UpdateTodo(todo, userId, false)
+		if err != nil {
+			return err
+		}
+
+		res := model.NewOKResponse(model.MSG_TODO_UPDATED)
+		res.AddObject("todo", dbTodo)
+		res.AddObject("affected", affected)
+		return res
+
+	default:
+		todoId, err := op.TodoID.Resolve("todoId", tempIds)
+		if err != nil {
+			return err
+		}
+
+		if op.Version == nil {
+			return model.NewPreconditionRequiredError(model.MSG_TODO_VERSION_MISSING)
+		}
+
+		token, err := app.DeleteTodo(todoId, userId, op.Version)
+		if err != nil {
+			return err
+		}
+
+		return model.NewOKResponse(model.MSG_TODO_DELETED).AddObject("undoToken", token)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+)
+
+func (api *API) InitEvents() {
+	api.Router.Events.Handle("", api.createProtectedHandler(api.StreamEvents, true)).Methods("GET")
+}
+
+// StreamEvents pushes the changes made to the todos of the user as
+// server-sent events until the client goes away. Events carry no id, as they
+// are not kept to be replayed: clients falling behind are dropped with a
+// reset event, and like any client reconnecting they should catch up through
+// /sync.
+func (api *API) StreamEvents(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return model.NewInternalError(model.MSG_EVENTS_UNSUPPORTED)
+	}
+
+	sub := api.App.Events.Subscribe(ctx.CurrentUser.ID)
+	defer api.App.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(model.EVENT_HEARTBEAT * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				fmt.Fprintf(w, "event: %s\ndata: {}\n\n", model.EVENT_STREAM_RESET)
+				flusher.Flush()
+				return nil
+			}
+
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
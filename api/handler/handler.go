@@ -2,9 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/jvitoroc/todo-go/app"
 	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
 )
 
 type Handler struct {
@@ -22,6 +24,8 @@ type RequestContext struct {
 	Request *http.Request
 }
 
+// HandlerFunc returns the response to write, or nil when it has already
+// written to the connection itself, as streaming handlers do.
 type HandlerFunc func(*RequestContext, http.ResponseWriter, *http.Request) Response
 
 func (hn *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -30,15 +34,63 @@ func (hn *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := hn.RunAllMiddlewares(ctx); err != nil {
-		writeResponse(w, err)
+		writeResponse(w, r, err)
 		return
 	}
 
-	res := hn.Handler(ctx, w, r)
-	writeResponse(w, res)
+	if res := hn.Handler(ctx, w, r); res != nil {
+		writeResponse(w, r, res)
+	}
 }
 
-func writeResponse(w http.ResponseWriter, res Response) {
+// writeResponse writes the response along with its headers. Successful GETs
+// get an ETag over their payload unless they carry one already, and are
+// answered with 304 Not Modified when the client copy is still current.
+func writeResponse(w http.ResponseWriter, r *http.Request, res Response) {
+	body := res.ToJson()
+	for key, values := range res.GetHeaders() {
+		w.Header()[key] = values
+	}
+
+	if r.Method == http.MethodGet && res.GetCode() == http.StatusOK {
+		if w.Header().Get("ETag") == "" {
+			w.Header().Set("ETag", `W/"`+util.Digest(body)+`"`)
+		}
+
+		if notModified(r, w.Header()) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	w.WriteHeader(res.GetCode())
-	w.Write([]byte(res.ToJson()))
+	w.Write([]byte(body))
+}
+
+// notModified checks If-None-Match against the ETag of the response using
+// the weak comparison, falling back to If-Modified-Since only when there is
+// no If-None-Match, as RFC 7232 requires.
+func notModified(r *http.Request, header http.Header) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := strings.TrimPrefix(header.Get("ETag"), "W/")
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
 }
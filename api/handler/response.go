@@ -1,6 +1,9 @@
 package handler
 
+import "net/http"
+
 type Response interface {
 	GetCode() int
+	GetHeaders() http.Header
 	ToJson() string
 }
@@ -10,6 +10,13 @@ type Router struct {
 	Session             *mux.Router
 	VerificationRequest *mux.Router
 	Todo                *mux.Router
+	Tag                 *mux.Router
+	View                *mux.Router
+	Undo                *mux.Router
+	Template            *mux.Router
+	Batch               *mux.Router
+	Sync                *mux.Router
+	Events              *mux.Router
 }
 
 func (api *API) setupRoutes() {
@@ -18,9 +25,23 @@ func (api *API) setupRoutes() {
 	api.Router.Session = api.Router.User.PathPrefix("/session").Subrouter()
 	api.Router.VerificationRequest = api.Router.User.PathPrefix("/verification-request").Subrouter()
 	api.Router.Todo = api.MainRouter.PathPrefix("/todo").Subrouter()
+	api.Router.Tag = api.MainRouter.PathPrefix("/tag").Subrouter()
+	api.Router.View = api.MainRouter.PathPrefix("/view").Subrouter()
+	api.Router.Undo = api.MainRouter.PathPrefix("/undo").Subrouter()
+	api.Router.Template = api.MainRouter.PathPrefix("/template").Subrouter()
+	api.Router.Batch = api.MainRouter.PathPrefix("/batch").Subrouter()
+	api.Router.Sync = api.MainRouter.PathPrefix("/sync").Subrouter()
+	api.Router.Events = api.MainRouter.PathPrefix("/events").Subrouter()
 
 	api.InitUser()
 	api.InitSession()
 	api.InitVerificationRequest()
 	api.InitTodo()
+	api.InitTag()
+	api.InitView()
+	api.InitUndo()
+	api.InitTemplate()
+	api.InitBatch()
+	api.InitSync()
+	api.InitEvents()
 }
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+func (api *API) InitSync() {
+	api.Router.Sync.Handle("", api.createProtectedHandler(api.SyncTodos, true)).Methods("GET")
+}
+
+// SyncTodos returns what changed since the given cursor, or everything when
+// there is none. Clients keep calling it with the returned cursor until
+// hasMore is false.
+func (api *API) SyncTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	var since int64
+	if value, ok := util.ExtractFormValue("since", r); ok {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil || since < 0 {
+			return model.NewFormError(nil).AddError("since", model.MSG_SYNC_CURSOR_INVALID)
+		}
+	}
+
+	limit, ok := util.ExtractFormInt("limit", r)
+	if !ok {
+		limit = model.SYNC_DEFAULT_LIMIT
+	}
+
+	if limit < 1 || limit > model.SYNC_MAXIMUM_LIMIT {
+		return model.NewFormError(nil).AddError("limit", fmt.Sprintf(model.MSG_TODO_LIMIT_INVALID, model.SYNC_MAXIMUM_LIMIT))
+	}
+
+	sync, err := api.App.SyncTodos(ctx.CurrentUser.ID, since, limit)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewOKResponse(model.MSG_SYNC_RETRIEVED)
+	res.AddObject("todos", sync.Todos)
+	res.AddObject("deleted", sync.Deleted)
+	res.AddObject("cursor", sync.Cursor)
+	res.AddObject("hasMore", sync.HasMore)
+	return res
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+func (api *API) InitTag() {
+	api.Router.Tag.Handle("", api.createProtectedHandler(api.CreateTag, true)).Methods("POST")
+	api.Router.Tag.Handle("", api.createProtectedHandler(api.GetTags, true)).Methods("GET")
+	api.Router.Tag.Handle("/{tagId:[0-9]+}", api.createProtectedHandler(api.GetTag, true)).Methods("GET")
+	api.Router.Tag.Handle("/{tagId:[0-9]+}", api.createProtectedHandler(api.UpdateTag, true)).Methods("PATCH")
+	api.Router.Tag.Handle("/{tagId:[0-9]+}", api.createProtectedHandler(api.DeleteTag, true)).Methods("DELETE")
+}
+
+func (api *API) CreateTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	tag, err := model.TagFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	tag.UserID = ctx.CurrentUser.ID
+
+	if err := api.App.CreateTag(tag); err != nil {
+		return err
+	}
+
+	return model.NewCreatedResponse(model.MSG_TAG_CREATED).AddObject("tag", tag)
+}
+
+func (api *API) GetTags(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	tags, err := api.App.GetTags(ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TAGS_RETRIEVED).AddObject("tags", tags)
+}
+
+func (api *API) GetTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	tagId, _ := util.ExtractParamInt("tagId", r)
+	tag, err := api.App.GetTag(tagId, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TAG_RETRIEVED).AddObject("tag", tag)
+}
+
+func (api *API) UpdateTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	tag, err := model.UpdateTagFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	tagId, _ := util.ExtractParamInt("tagId", r)
+	tag.ID = tagId
+	dbTag, err := api.App.UpdateTag(tag, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TAG_UPDATED).AddObject("tag", dbTag)
+}
+
+func (api *API) DeleteTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	tagId, _ := util.ExtractParamInt("tagId", r)
+	if err := api.App.DeleteTag(tagId, ctx.CurrentUser.ID); err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TAG_DELETED)
+}
+
+func (api *API) AddTodoTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	tagId, _ := util.ExtractParamInt("tagId", r)
+	todo, err := api.App.AddTodoTag(todoId, tagId, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODO_TAG_ADDED).AddObject("todo", todo)
+}
+
+func (api *API) RemoveTodoTag(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	tagId, _ := util.ExtractParamInt("tagId", r)
+	todo, err := api.App.RemoveTodoTag(todoId, tagId, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODO_TAG_REMOVED).AddObject("todo", todo)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+func (api *API) InitTemplate() {
+	api.Router.Template.Handle("", api.createProtectedHandler(api.CreateTemplate, true)).Methods("POST")
+	api.Router.Template.Handle("", api.createProtectedHandler(api.GetTemplates, true)).Methods("GET")
+	api.Router.Template.Handle("/{templateId:[0-9]+}", api.createProtectedHandler(api.GetTemplate, true)).Methods("GET")
+	api.Router.Template.Handle("/{templateId:[0-9]+}", api.createProtectedHandler(api.DeleteTemplate, true)).Methods("DELETE")
+	api.Router.Template.Handle("/{templateId:[0-9]+}/instantiate", api.createProtectedHandler(api.InstantiateTemplate, true)).Methods("POST")
+}
+
+func (api *API) CreateTemplate(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	create, err := model.CreateTemplateFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := create.Validate(); err != nil {
+		return err
+	}
+
+	template, err := api.App.CreateTemplate(create, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewCreatedResponse(model.MSG_TEMPLATE_CREATED).AddObject("template", template)
+}
+
+func (api *API) GetTemplates(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	templates, err := api.App.GetTemplates(ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TEMPLATES_RETRIEVED).AddObject("templates", templates)
+}
+
+func (api *API) GetTemplate(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	templateId, _ := util.ExtractParamInt("templateId", r)
+	template, err := api.App.GetTemplate(templateId, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TEMPLATE_RETRIEVED).AddObject("template", template)
+}
+
+func (api *API) DeleteTemplate(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	templateId, _ := util.ExtractParamInt("templateId", r)
+	if err := api.App.DeleteTemplate(templateId, ctx.CurrentUser.ID); err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TEMPLATE_DELETED)
+}
+
+func (api *API) InstantiateTemplate(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	instantiate, err := model.InstantiateTemplateFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := instantiate.Validate(); err != nil {
+		return err
+	}
+
+	templateId, _ := util.ExtractParamInt("templateId", r)
+	instantiate.ID = templateId
+	todo, count, err := api.App.InstantiateTemplate(instantiate, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewCreatedResponse(model.MSG_TEMPLATE_INSTANTIATED)
+	res.AddObject("todo", todo)
+	res.AddObject("created", count)
+	return res
+}
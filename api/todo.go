@@ -1,7 +1,11 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	hn "github.com/jvitoroc/todo-go/api/handler"
 	"github.com/jvitoroc/todo-go/model"
@@ -11,9 +15,18 @@ import (
 func (api *API) InitTodo() {
 	api.Router.Todo.Handle("/{todoId:[0-9]*}", api.createProtectedHandler(api.CreateTodo, true)).Methods("POST")
 	api.Router.Todo.Handle("", api.createProtectedHandler(api.GetRootTodoChildren, true)).Methods("GET")
+	api.Router.Todo.Handle("/search", api.createProtectedHandler(api.SearchTodos, true)).Methods("GET")
+	api.Router.Todo.Handle("/trash", api.createProtectedHandler(api.GetTrashedTodos, true)).Methods("GET")
 	api.Router.Todo.Handle("/{todoId:[0-9]+}", api.createProtectedHandler(api.GetTodo, true)).Methods("GET")
 	api.Router.Todo.Handle("/{todoId:[0-9]+}", api.createProtectedHandler(api.UpdateTodo, true)).Methods("PATCH")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/move", api.createProtectedHandler(api.MoveTodo, true)).Methods("POST")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/reorder", api.createProtectedHandler(api.ReorderTodo, true)).Methods("POST")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/restore", api.createProtectedHandler(api.RestoreTodo, true)).Methods("POST")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/duplicate", api.createProtectedHandler(api.DuplicateTodo, true)).Methods("POST")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/tag/{tagId:[0-9]+}", api.createProtectedHandler(api.AddTodoTag, true)).Methods("POST")
+	api.Router.Todo.Handle("/{todoId:[0-9]+}/tag/{tagId:[0-9]+}", api.createProtectedHandler(api.RemoveTodoTag, true)).Methods("DELETE")
 	api.Router.Todo.Handle("/{todoId:[0-9]+}", api.createProtectedHandler(api.DeleteTodo, true)).Methods("DELETE")
+	api.Router.Todo.Handle("", api.createProtectedHandler(api.UpdateManyTodos, true)).Methods("PATCH")
 	api.Router.Todo.Handle("", api.createProtectedHandler(api.DeleteManyTodos, true)).Methods("DELETE")
 }
 
@@ -55,32 +68,120 @@ func (api *API) GetTodo(hn *hn.RequestContext, w http.ResponseWriter, r *http.Re
 
 	parents := make([]model.Todo, 0) // allocate array so we can return at least a empty array to the client
 	if todo.ParentTodoID != nil {
-		parents, err = api.App.GetTodosBottomToTop(*todo.ParentTodoID, hn.CurrentUser.ID, parentsCount)
+		parents, err = api.App.GetTodoAncestors(*todo.ParentTodoID, hn.CurrentUser.ID, parentsCount)
 		if err != nil {
 			return err
 		}
 	}
 
-	todos, err := api.App.GetTodoChildren(todoId, hn.CurrentUser.ID)
+	sort, err := todoSortFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	page, err := todoPageFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var todos interface{}
+	var next *string
+	if depth, ok := extractSubtreeDepth(r); ok {
+		todos, err = api.App.GetTodoSubtree(&todoId, hn.CurrentUser.ID, depth, sort)
+	} else {
+		todos, next, err = api.App.GetTodoChildren(todoId, hn.CurrentUser.ID, sort, page)
+	}
 	if err != nil {
 		return err
 	}
 
 	res := model.NewOKResponse(model.MSG_TODO_RETRIEVED)
+	res.AddObject("nextCursor", next)
 	res.AddObject("children", todos)
 	res.AddObject("todo", todo)
 	res.AddObject("parents", parents)
+	res.SetHeader("ETag", todo.PayloadETag(res.ToJson()))
+
+	if err := api.setTodosLastModified(res, hn.CurrentUser.ID); err != nil {
+		return err
+	}
 
 	return res
 }
 
 func (api *API) GetRootTodoChildren(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
-	todos, err := api.App.GetRootTodoChildren(ctx.CurrentUser.ID)
+	filter, err := todoFilterFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	sort, err := todoSortFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	page, err := todoPageFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if !filter.IsEmpty() {
+		todos, next, err := api.App.GetFilteredTodos(ctx.CurrentUser.ID, filter, sort, page)
+		if err != nil {
+			return err
+		}
+
+		res := model.NewOKResponse(model.MSG_TODO_RETRIEVED)
+		res.AddObject("todos", todos)
+		res.AddObject("nextCursor", next)
+
+		if err := api.setTodosLastModified(res, ctx.CurrentUser.ID); err != nil {
+			return err
+		}
+
+		return res
+	}
+
+	var todos interface{}
+	var next *string
+	if depth, ok := extractSubtreeDepth(r); ok {
+		todos, err = api.App.GetTodoSubtree(nil, ctx.CurrentUser.ID, depth, sort)
+	} else {
+		todos, next, err = api.App.GetRootTodoChildren(ctx.CurrentUser.ID, sort, page)
+	}
 	if err != nil {
 		return err
 	}
 
-	return model.NewOKResponse(model.MSG_TODO_RETRIEVED).AddObject("children", todos)
+	res := model.NewOKResponse(model.MSG_TODO_RETRIEVED)
+	res.AddObject("children", todos)
+	res.AddObject("nextCursor", next)
+
+	if err := api.setTodosLastModified(res, ctx.CurrentUser.ID); err != nil {
+		return err
+	}
+
+	return res
+}
+
+func (api *API) SearchTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	query, _ := util.ExtractFormValue("q", r)
+
+	limit, ok := util.ExtractFormInt("limit", r)
+	if !ok {
+		limit = model.TODO_SEARCH_DEFAULT_LIMIT
+	}
+
+	if limit < 1 || limit > model.TODO_SEARCH_MAXIMUM_LIMIT {
+		return model.NewFormError(nil).AddError("limit", fmt.Sprintf(model.MSG_TODO_LIMIT_INVALID, model.TODO_SEARCH_MAXIMUM_LIMIT))
+	}
+
+	results, err := api.App.SearchTodos(query, ctx.CurrentUser.ID, limit)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODO_RETRIEVED).AddObject("results", results)
 }
 
 func (api *API) UpdateTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
@@ -93,24 +194,135 @@ func (api *API) UpdateTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *htt
 		return err
 	}
 
+	cascade, _ := util.ExtractFormBool("cascade", r)
+	if cascade && todo.Completed == nil {
+		return model.NewFormError(nil).AddError("cascade", model.MSG_TODO_CASCADE_INVALID)
+	}
+
 	todoId, _ := util.ExtractParamInt("todoId", r)
+	if todo.Version, err = api.todoVersionFromRequest(r, todoId, ctx.CurrentUser.ID, todo.Version); err != nil {
+		return err
+	}
+
 	todo.ID = todoId
-	dbTodo, err := api.App.UpdateTodo(todo, ctx.CurrentUser.ID)
+	dbTodo, affected, err := api.App.UpdateTodo(todo, ctx.CurrentUser.ID, cascade)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewOKResponse(model.MSG_TODO_UPDATED)
+	res.AddObject("todo", dbTodo)
+	res.AddObject("affected", affected)
+	res.SetHeader("ETag", dbTodo.ETag())
+	return res
+}
+
+func (api *API) UpdateManyTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	update, err := model.UpdateManyTodosFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := update.Validate(); err != nil {
+		return err
+	}
+
+	if err := model.CheckTodoVersionsGiven(update.IDs, update.Versions); err != nil {
+		return err
+	}
+
+	todos, err := api.App.UpdateManyTodos(update, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODOS_UPDATED).AddObject("todos", todos)
+}
+
+func (api *API) MoveTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	move, err := model.MoveTodoFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	if move.Version, err = api.todoVersionFromRequest(r, todoId, ctx.CurrentUser.ID, move.Version); err != nil {
+		return err
+	}
+
+	move.ID = todoId
+	dbTodo, token, err := api.App.MoveTodo(move, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewOKResponse(model.MSG_TODO_MOVED)
+	res.AddObject("todo", dbTodo)
+	res.AddObject("undoToken", token)
+	return res
+}
+
+func (api *API) DuplicateTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	duplicate, err := model.DuplicateTodoFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	duplicate.ID = todoId
+	todo, count, err := api.App.DuplicateTodo(duplicate, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewCreatedResponse(model.MSG_TODO_DUPLICATED)
+	res.AddObject("todo", todo)
+	res.AddObject("created", count)
+	return res
+}
+
+func (api *API) ReorderTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	reorder, err := model.ReorderTodoFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := reorder.Validate(); err != nil {
+		return err
+	}
+
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	if reorder.Version, err = api.todoVersionFromRequest(r, todoId, ctx.CurrentUser.ID, reorder.Version); err != nil {
+		return err
+	}
+
+	reorder.ID = todoId
+	dbTodo, err := api.App.ReorderTodo(reorder, ctx.CurrentUser.ID)
 	if err != nil {
 		return err
 	}
 
-	return model.NewOKResponse(model.MSG_TODO_UPDATED).AddObject("todo", dbTodo)
+	return model.NewOKResponse(model.MSG_TODO_REORDERED).AddObject("todo", dbTodo)
 }
 
 func (api *API) DeleteTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	var version *int
+	if value, ok := util.ExtractFormInt("version", r); ok {
+		version = &value
+	}
+
 	todoId, _ := util.ExtractParamInt("todoId", r)
-	err := api.App.DeleteTodo(todoId, ctx.CurrentUser.ID)
+	version, err := api.todoVersionFromRequest(r, todoId, ctx.CurrentUser.ID, version)
 	if err != nil {
 		return err
 	}
 
-	return model.NewOKResponse(model.MSG_TODO_DELETED)
+	token, err := api.App.DeleteTodo(todoId, ctx.CurrentUser.ID, version)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODO_DELETED).AddObject("undoToken", token)
 }
 
 func (api *API) DeleteManyTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
@@ -119,13 +331,189 @@ func (api *API) DeleteManyTodos(ctx *hn.RequestContext, w http.ResponseWriter, r
 		return err
 	}
 
-	if delete.Validate(); err != nil {
+	if err := delete.Validate(); err != nil {
+		return err
+	}
+
+	if err := model.CheckTodoVersionsGiven(delete.IDs, delete.Versions); err != nil {
+		return err
+	}
+
+	token, err := api.App.DeleteManyTodos(delete.IDs, delete.Versions, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_TODOS_DELETED).AddObject("undoToken", token)
+}
+
+func (api *API) GetTrashedTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	todos, err := api.App.GetTrashedTodos(ctx.CurrentUser.ID)
+	if err != nil {
 		return err
 	}
 
-	if err := api.App.DeleteManyTodos(delete.IDs, ctx.CurrentUser.ID); err != nil {
+	return model.NewOKResponse(model.MSG_TODO_RETRIEVED).AddObject("todos", todos)
+}
+
+func (api *API) RestoreTodo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	todoId, _ := util.ExtractParamInt("todoId", r)
+	todo, restored, err := api.App.RestoreTodo(todoId, ctx.CurrentUser.ID)
+	if err != nil {
 		return err
 	}
 
-	return model.NewOKResponse(model.MSG_TODOS_DELETED)
+	res := model.NewOKResponse(model.MSG_TODO_RESTORED)
+	res.AddObject("todo", todo)
+	res.AddObject("restored", restored)
+	return res
+}
+
+// extractSubtreeDepth reads the "depth" and "full" query params. Since a subtree
+// can never be deeper than its node count, "full" is bounded by the node cap.
+func extractSubtreeDepth(r *http.Request) (int, bool) {
+	if full, _ := util.ExtractFormBool("full", r); full {
+		return model.TODO_SUBTREE_MAXIMUM_NODES, true
+	}
+
+	if depth, ok := util.ExtractFormInt("depth", r); ok && depth > 0 {
+		return depth, true
+	}
+
+	return 0, false
+}
+
+func todoFilterFromRequest(r *http.Request) (*model.TodoFilter, *model.AppError) {
+	due, _ := util.ExtractFormValue("due", r)
+	tags, _ := util.ExtractFormValues("tag", r)
+	tz, _ := util.ExtractFormValue("tz", r)
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, model.NewBadRequestError(fmt.Sprintf(model.MSG_TIMEZONE_INVALID, tz))
+	}
+
+	filter := &model.TodoFilter{Due: due, Location: loc, Tags: tags}
+	if expression, ok := util.ExtractFormValue("filter", r); ok {
+		parsed, err := model.ParseTodoFilter(expression, loc)
+		if err != nil {
+			return nil, err
+		}
+		filter.Expression = parsed
+	}
+
+	return filter, nil
+}
+
+func todoSortFromRequest(r *http.Request) (*model.TodoSort, *model.AppError) {
+	field, _ := util.ExtractFormValue("sort", r)
+	order, _ := util.ExtractFormValue("order", r)
+
+	return model.NewTodoSort(field, order)
+}
+
+// todoVersionFromRequest returns the version the client expects the todo to
+// be at, read from If-Match or else from the given version. Changing a todo
+// requires one of them, while If-Match: * allows any version. If-Match may list
+// several ETags, the one matching the todo being returned, and only strong
+// ones are compared so that weak ETags never match. ETags of whole responses
+// hold the version of the todo before a dot.
+func (api *API) todoVersionFromRequest(r *http.Request, todoId, userId int, version *int) (*int, *model.AppError) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case header == "*":
+		return nil, nil
+	case header == "" && version == nil:
+		return nil, model.NewPreconditionRequiredError(model.MSG_TODO_VERSION_MISSING)
+	case header == "":
+		return version, nil
+	}
+
+	versions, err := parseTodoVersions(header)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single version is checked along with the change itself
+	if len(versions) == 1 {
+		return &versions[0], nil
+	}
+
+	todo, err := api.App.GetTodo(todoId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		if versions[i] == todo.Version {
+			return &versions[i], nil
+		}
+	}
+
+	return nil, model.NewPreconditionFailedError(model.MSG_TODO_VERSION_NO_MATCH).AddObject("todo", todo)
+}
+
+// parseTodoVersions returns the versions held by the strong ETags of an
+// If-Match list, leaving out the weak ones.
+func parseTodoVersions(header string) ([]int, *model.AppError) {
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, model.NewFormError(nil).AddError("If-Match", model.MSG_TODO_VERSION_INVALID)
+		}
+
+		if weak {
+			continue
+		}
+
+		tag = tag[1 : len(tag)-1]
+		if dot := strings.IndexByte(tag, '.'); dot >= 0 {
+			tag = tag[:dot]
+		}
+
+		value, err := strconv.Atoi(tag)
+		if err != nil {
+			return nil, model.NewFormError(nil).AddError("If-Match", model.MSG_TODO_VERSION_INVALID)
+		}
+		versions = append(versions, value)
+	}
+
+	return versions, nil
+}
+
+// setTodosLastModified dates the response by the last change to any todo of
+// the user, which is conservative but holds for every todo listing. HTTP dates
+// only go down to the second, so a change made less than a second ago leaves
+// the response undated, as another change within the same second would not
+// move its date. Clients then revalidate with the ETag alone.
+func (api *API) setTodosLastModified(res *model.AppResponse, userId int) *model.AppError {
+	changed, err := api.App.GetTodosLastChanged(userId)
+	if err != nil {
+		return err
+	}
+
+	if changed != nil && time.Since(*changed) >= time.Second {
+		res.SetHeader("Last-Modified", changed.UTC().Format(http.TimeFormat))
+	}
+
+	return nil
+}
+
+func todoPageFromRequest(r *http.Request) (*model.TodoPage, *model.AppError) {
+	limit, _ := util.ExtractFormInt("limit", r)
+	cursor, _ := util.ExtractFormValue("cursor", r)
+
+	page := model.NewTodoPage(limit, cursor)
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
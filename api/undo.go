@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+func (api *API) InitUndo() {
+	api.Router.Undo.Handle("/{token}", api.createProtectedHandler(api.Undo, true)).Methods("POST")
+}
+
+func (api *API) Undo(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	token, _ := util.ExtractParam("token", r)
+	ids, err := api.App.Undo(token, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_UNDONE).AddObject("todoIds", ids)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+
+	hn "github.com/jvitoroc/todo-go/api/handler"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+func (api *API) InitView() {
+	api.Router.View.Handle("", api.createProtectedHandler(api.CreateView, true)).Methods("POST")
+	api.Router.View.Handle("", api.createProtectedHandler(api.GetViews, true)).Methods("GET")
+	api.Router.View.Handle("/{viewId:[0-9]+}", api.createProtectedHandler(api.GetView, true)).Methods("GET")
+	api.Router.View.Handle("/{viewId:[0-9]+}/todos", api.createProtectedHandler(api.GetViewTodos, true)).Methods("GET")
+	api.Router.View.Handle("/{viewId:[0-9]+}", api.createProtectedHandler(api.UpdateView, true)).Methods("PATCH")
+	api.Router.View.Handle("/{viewId:[0-9]+}", api.createProtectedHandler(api.DeleteView, true)).Methods("DELETE")
+}
+
+func (api *API) CreateView(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	view, err := model.ViewFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := view.Validate(); err != nil {
+		return err
+	}
+
+	view.UserID = ctx.CurrentUser.ID
+
+	if err := api.App.CreateView(view); err != nil {
+		return err
+	}
+
+	return model.NewCreatedResponse(model.MSG_VIEW_CREATED).AddObject("view", view)
+}
+
+func (api *API) GetViews(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	views, err := api.App.GetViews(ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_VIEWS_RETRIEVED).AddObject("views", views)
+}
+
+func (api *API) GetView(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	viewId, _ := util.ExtractParamInt("viewId", r)
+	view, err := api.App.GetView(viewId, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_VIEW_RETRIEVED).AddObject("view", view)
+}
+
+func (api *API) GetViewTodos(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	page, err := todoPageFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	viewId, _ := util.ExtractParamInt("viewId", r)
+	todos, next, err := api.App.GetViewTodos(viewId, ctx.CurrentUser.ID, page)
+	if err != nil {
+		return err
+	}
+
+	res := model.NewOKResponse(model.MSG_TODO_RETRIEVED)
+	res.AddObject("todos", todos)
+	res.AddObject("nextCursor", next)
+	return res
+}
+
+func (api *API) UpdateView(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	view, err := model.UpdateViewFromJson(r.Body)
+	if err != nil {
+		return err
+	}
+
+	viewId, _ := util.ExtractParamInt("viewId", r)
+	view.ID = viewId
+	dbView, err := api.App.UpdateView(view, ctx.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_VIEW_UPDATED).AddObject("view", dbView)
+}
+
+func (api *API) DeleteView(ctx *hn.RequestContext, w http.ResponseWriter, r *http.Request) hn.Response {
+	viewId, _ := util.ExtractParamInt("viewId", r)
+	if err := api.App.DeleteView(viewId, ctx.CurrentUser.ID); err != nil {
+		return err
+	}
+
+	return model.NewOKResponse(model.MSG_VIEW_DELETED)
+}
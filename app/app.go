@@ -4,21 +4,87 @@ import (
 	"github.com/jvitoroc/todo-go/auth"
 	"github.com/jvitoroc/todo-go/config"
 	"github.com/jvitoroc/todo-go/email"
+	"github.com/jvitoroc/todo-go/event"
+	"github.com/jvitoroc/todo-go/model"
 	"github.com/jvitoroc/todo-go/repository"
 )
 
 type App struct {
 	Repository   *repository.Repository
-	EmailService *email.EmailService
+	EmailService email.Sender
 	AuthService  *auth.AuthService
 	Config       *config.Config
+	Events       *event.Hub
+
+	pending *[]pendingEvent // events held until the batch being run commits
+}
+
+type pendingEvent struct {
+	userId int
+	event  *model.TodoEvent
 }
 
-func NewApp(repo *repository.Repository, email *email.EmailService, auth *auth.AuthService, config *config.Config) *App {
+func NewApp(repo *repository.Repository, email email.Sender, auth *auth.AuthService, config *config.Config) *App {
 	return &App{
 		Repository:   repo,
 		EmailService: email,
 		AuthService:  auth,
 		Config:       config,
+		Events:       event.NewHub(),
+	}
+}
+
+// todoChanges collects the todos a change affects besides the ones it was made
+// to, such as the ancestors it completes or the todos it copies, so that their
+// events go out along with its own.
+type todoChanges struct {
+	created []int
+	updated []int
+}
+
+func (c *todoChanges) create(todoIds ...int) {
+	c.created = append(c.created, todoIds...)
+}
+
+func (c *todoChanges) update(todoIds ...int) {
+	c.updated = append(c.updated, todoIds...)
+}
+
+// publishChanges publishes the collected changes, leaving out the todos whose
+// events were published already.
+func (app *App) publishChanges(userId int, changes *todoChanges, published ...int) {
+	seen := make(map[int]bool, len(published))
+	for _, id := range published {
+		seen[id] = true
+	}
+
+	unseen := func(todoIds []int) []int {
+		ids := []int{}
+		for _, id := range todoIds {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids
 	}
+
+	if created := unseen(changes.created); len(created) > 0 {
+		app.publish(userId, model.NewTodoIDsEvent(model.EVENT_TODO_CREATED, created))
+	}
+
+	if updated := unseen(changes.updated); len(updated) > 0 {
+		app.publish(userId, model.NewTodoIDsEvent(model.EVENT_TODO_UPDATED, updated))
+	}
+}
+
+// publish sends the event to the clients of the user. It must only be called
+// once the change is committed, which for batches means holding it back.
+func (app *App) publish(userId int, event *model.TodoEvent) {
+	if app.pending != nil {
+		*app.pending = append(*app.pending, pendingEvent{userId, event})
+		return
+	}
+
+	app.Events.Publish(userId, event)
 }
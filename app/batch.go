@@ -0,0 +1,29 @@
+package app
+
+import (
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+// Batch runs fn with a copy of the app whose operations all join a single
+// transaction, committed only if fn returns no error. Events are published
+// once the transaction is committed.
+func (app *App) Batch(fn func(*App) *model.AppError) *model.AppError {
+	pending := []pendingEvent{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		batch := *app
+		batch.Repository = tran
+		batch.pending = &pending
+		return fn(&batch)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		app.publish(p.userId, p.event)
+	}
+
+	return nil
+}
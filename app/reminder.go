@@ -0,0 +1,77 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+)
+
+// SendDueReminders emails the owner of every todo whose reminder is due at the
+// given time. Reminders are claimed before being sent and only marked as sent
+// once the email is delivered, while claims left by a sender that stopped
+// midway go stale after REMINDER_CLAIM_TIMEOUT and are claimed again. Failed
+// emails are released to be retried later, backing off on every failure until
+// REMINDER_MAX_ATTEMPTS is reached and the reminder is given up on. The first
+// delivery error, if any, is returned once the whole batch is done.
+func (app *App) SendDueReminders(now time.Time) *model.AppError {
+	todos, err := app.Repository.GetPendingReminders(now.UTC(), model.REMINDER_BATCH_SIZE)
+	if err != nil {
+		return err
+	}
+
+	var sendErr *model.AppError
+	for i := range todos {
+		claimed, err := app.Repository.ClaimReminder(todos[i].ID, now.UTC())
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		// keep going so a single undeliverable email doesn't hold back the others
+		if err := app.SendReminderEmail(&todos[i]); err != nil {
+			if releaseErr := app.retryReminder(&todos[i], now.UTC()); releaseErr != nil {
+				return releaseErr
+			}
+			if sendErr == nil {
+				sendErr = err
+			}
+			continue
+		}
+
+		if err := app.Repository.MarkReminderSent(todos[i].ID, now.UTC()); err != nil {
+			return err
+		}
+	}
+
+	return sendErr
+}
+
+// retryReminder schedules another send of a reminder that failed, or gives up
+// on it once it has failed too many times.
+func (app *App) retryReminder(todo *model.Todo, now time.Time) *model.AppError {
+	attempts := todo.ReminderAttempts + 1
+	if attempts >= model.REMINDER_MAX_ATTEMPTS {
+		log.Printf("Giving up on the reminder of todo %d after %d failed attempts", todo.ID, attempts)
+		return app.Repository.FailReminder(todo.ID, now)
+	}
+
+	delay := time.Duration(model.REMINDER_RETRY_DELAY<<(attempts-1)) * time.Second
+	return app.Repository.ReleaseReminder(todo.ID, now.Add(delay))
+}
+
+func (app *App) SendReminderEmail(todo *model.Todo) *model.AppError {
+	body := "Hey " + todo.User.Username + ", this is a reminder for your todo: " + todo.Description
+	if todo.DueAt != nil {
+		body = body + "\r\n" + "It is due on " + todo.DueAt.Format(time.RFC1123)
+	}
+
+	if err := app.EmailService.SendEmail(todo.User.Email, "Todo App: reminder for "+todo.Description, body); err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
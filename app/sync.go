@@ -0,0 +1,63 @@
+package app
+
+import (
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+// GetTodosLastChanged returns when any todo of the user last changed, for
+// clients to check whether what they have is still current.
+func (app *App) GetTodosLastChanged(userId int) (*time.Time, *model.AppError) {
+	return app.Repository.GetTodosLastChanged(userId)
+}
+
+// SyncTodos returns the todos of the user changed after the given cursor,
+// with those deleted since then listed apart, by id only.
+func (app *App) SyncTodos(userId int, since int64, limit int) (*model.TodoSync, *model.AppError) {
+	sync := &model.TodoSync{Todos: []model.Todo{}, Deleted: []int{}, Cursor: since}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		changes, err := tran.GetTodoChanges(userId, since, limit+1)
+		if err != nil {
+			return err
+		}
+
+		if len(changes) > limit {
+			changes = changes[:limit]
+			sync.HasMore = true
+		}
+
+		ids := make([]int, len(changes))
+		for i := range changes {
+			ids[i] = changes[i].TodoID
+		}
+
+		todos, err := tran.GetTodosUnscoped(ids, userId)
+		if err != nil {
+			return err
+		}
+
+		found := make(map[int]*model.Todo, len(todos))
+		for i := range todos {
+			found[todos[i].ID] = &todos[i]
+		}
+
+		for _, change := range changes {
+			if todo, ok := found[change.TodoID]; ok && !todo.DeletedAt.Valid {
+				sync.Todos = append(sync.Todos, *todo)
+			} else {
+				sync.Deleted = append(sync.Deleted, change.TodoID)
+			}
+			sync.Cursor = change.Sequence
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sync, nil
+}
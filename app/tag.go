@@ -0,0 +1,141 @@
+package app
+
+import (
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+func (app *App) CreateTag(tag *model.Tag) *model.AppError {
+	return app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		nameExists, err := tran.CheckIfTagNameExists(tag.Name, tag.UserID)
+		if err != nil {
+			return err
+		}
+
+		if nameExists {
+			return model.NewFormError(nil).AddError("name", model.MSG_TAG_ALREADY_EXISTS)
+		}
+
+		_, err = tran.CreateTag(tag)
+		return err
+	})
+}
+
+func (app *App) GetTag(tagId, userId int) (*model.Tag, *model.AppError) {
+	return app.Repository.GetTag(tagId, userId)
+}
+
+func (app *App) GetTags(userId int) ([]model.Tag, *model.AppError) {
+	return app.Repository.GetTags(userId)
+}
+
+// UpdateTag renames or recolors a tag, which changes every todo carrying it as
+// far as clients are concerned.
+func (app *App) UpdateTag(tag *model.UpdateTag, userId int) (*model.Tag, *model.AppError) {
+	var dbTag *model.Tag
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		dbTag, err = tran.GetTag(tag.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		if tag.Name != nil && *tag.Name != dbTag.Name {
+			nameExists, err := tran.CheckIfTagNameExists(*tag.Name, userId)
+			if err != nil {
+				return err
+			}
+
+			if nameExists {
+				return model.NewFormError(nil).AddError("name", model.MSG_TAG_ALREADY_EXISTS)
+			}
+
+			dbTag.Name = *tag.Name
+		}
+
+		if tag.Color != nil {
+			dbTag.Color = *tag.Color
+		}
+
+		if err := tran.UpdateTag(dbTag); err != nil {
+			return err
+		}
+
+		tagged, err := tran.GetTaggedTodoIDs(dbTag.ID)
+		if err != nil {
+			return err
+		}
+		changes.update(tagged...)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	app.publishChanges(userId, changes)
+	return dbTag, nil
+}
+
+// DeleteTag deletes a tag, taking it off every todo carrying it.
+func (app *App) DeleteTag(tagId, userId int) *model.AppError {
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		tagged, err := tran.GetTaggedTodoIDs(tagId)
+		if err != nil {
+			return err
+		}
+		changes.update(tagged...)
+
+		return tran.DeleteTag(tagId, userId)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	app.publishChanges(userId, changes)
+	return nil
+}
+
+func (app *App) AddTodoTag(todoId, tagId, userId int) (*model.Todo, *model.AppError) {
+	return app.changeTodoTag(todoId, tagId, userId, (*repository.Repository).AddTodoTag)
+}
+
+func (app *App) RemoveTodoTag(todoId, tagId, userId int) (*model.Todo, *model.AppError) {
+	return app.changeTodoTag(todoId, tagId, userId, (*repository.Repository).RemoveTodoTag)
+}
+
+// changeTodoTag checks that both the todo and the tag belong to the user
+// before applying the given change and reloading the todo.
+func (app *App) changeTodoTag(todoId, tagId, userId int, change func(*repository.Repository, *model.Todo, *model.Tag) *model.AppError) (*model.Todo, *model.AppError) {
+	var todo *model.Todo
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		todo, err = tran.GetTodo(todoId, userId)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tran.GetTag(tagId, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := change(tran, todo, tag); err != nil {
+			return err
+		}
+
+		todo, err = tran.GetTodo(todoId, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_UPDATED, todo))
+	return todo, nil
+}
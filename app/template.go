@@ -0,0 +1,181 @@
+package app
+
+import (
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+// CreateTemplate saves the given todo and its subtree as a template. Dates
+// are kept relative to the due date of the todo, or to now when it has none.
+func (app *App) CreateTemplate(create *model.CreateTemplate, userId int) (*model.Template, *model.AppError) {
+	var template *model.Template
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		todo, err := tran.GetTodo(create.TodoID, userId)
+		if err != nil {
+			return err
+		}
+
+		root, err := loadTodoTree(tran, todo, userId)
+		if err != nil {
+			return err
+		}
+
+		template, err = tran.CreateTemplate(&model.Template{UserID: userId, Name: create.Name})
+		if err != nil {
+			return err
+		}
+
+		start := time.Now().UTC()
+		if todo.DueAt != nil {
+			start = *todo.DueAt
+		}
+
+		if err := createTemplateItem(tran, root, template.ID, nil, start); err != nil {
+			return err
+		}
+
+		template, err = tran.GetTemplate(template.ID, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// createTemplateItem saves the node as an item of the template, followed by
+// its children, so that parent items always have lower ids.
+func createTemplateItem(tran *repository.Repository, node *model.TodoNode, templateId int, parentItemId *int, start time.Time) *model.AppError {
+	item := &model.TemplateItem{
+		TemplateID:   templateId,
+		ParentItemID: parentItemId,
+		Description:  node.Description,
+		Notes:        node.Notes,
+		AutoComplete: node.AutoComplete,
+		Priority:     node.Priority,
+		Position:     node.Position,
+		DueOffset:    model.NewTemplateOffset(node.DueAt, start),
+		RemindOffset: model.NewTemplateOffset(node.RemindAt, start),
+		Recurrence:   node.Recurrence,
+		Tags:         node.Tags,
+	}
+
+	if _, err := tran.CreateTemplateItem(item); err != nil {
+		return err
+	}
+
+	for _, child := range node.Children {
+		if err := createTemplateItem(tran, child, templateId, &item.ID, start); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (app *App) GetTemplate(templateId, userId int) (*model.Template, *model.AppError) {
+	return app.Repository.GetTemplate(templateId, userId)
+}
+
+func (app *App) GetTemplates(userId int) ([]model.Template, *model.AppError) {
+	return app.Repository.GetTemplates(userId)
+}
+
+func (app *App) DeleteTemplate(templateId, userId int) *model.AppError {
+	return app.Repository.DeleteTemplate(templateId, userId)
+}
+
+// InstantiateTemplate creates a todo for each item of the template, placing
+// the root on top of its new siblings. It returns the root todo along with
+// how many todos were created.
+func (app *App) InstantiateTemplate(instantiate *model.InstantiateTemplate, userId int) (*model.Todo, int, *model.AppError) {
+	var root *model.Todo
+	count := 0
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		template, err := tran.GetTemplate(instantiate.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		parentId := instantiate.ParentTodoID.Value
+		if parentId != nil {
+			if _, err := tran.GetTodo(*parentId, userId); err != nil {
+				return err
+			}
+		}
+
+		start := time.Now().UTC()
+		if instantiate.StartAt != nil {
+			start = instantiate.StartAt.UTC()
+		}
+
+		// items come parent first, so the todo of a parent item always
+		// exists by the time its children are created
+		todoIds := make(map[int]int, len(template.Items))
+		for _, item := range template.Items {
+			todo := &model.Todo{
+				ParentTodoID: parentId,
+				UserID:       userId,
+				Description:  instantiate.Substitute(item.Description, start),
+				Notes:        instantiate.Substitute(item.Notes, start),
+				AutoComplete: item.AutoComplete,
+				Priority:     item.Priority,
+				Position:     item.Position,
+				DueAt:        model.TemplateOffsetDate(item.DueOffset, start),
+				RemindAt:     model.TemplateOffsetDate(item.RemindOffset, start),
+				Recurrence:   item.Recurrence,
+			}
+
+			if item.ParentItemID != nil {
+				todoId := todoIds[*item.ParentItemID]
+				todo.ParentTodoID = &todoId
+			} else if todo.Position, err = topPosition(tran, parentId, userId); err != nil {
+				return err
+			}
+
+			if _, err := tran.CreateTodo(todo); err != nil {
+				return err
+			}
+
+			if len(item.Tags) > 0 {
+				if err := tran.SetTodoTags(todo, item.Tags); err != nil {
+					return err
+				}
+			}
+
+			if root == nil {
+				root = todo
+			}
+			todoIds[item.ID] = todo.ID
+			changes.create(todo.ID)
+			count++
+		}
+
+		if root == nil {
+			return nil
+		}
+
+		if err := settleAutoCompletion(tran, parentId, userId, changes); err != nil {
+			return err
+		}
+
+		root, err = tran.GetTodo(root.ID, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if root != nil {
+		app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_CREATED, root))
+		app.publishChanges(userId, changes, root.ID)
+	}
+
+	return root, count, nil
+}
@@ -1,79 +1,1065 @@
 package app
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+	"github.com/jvitoroc/todo-go/util"
+	"gorm.io/gorm"
 )
 
 func (app *App) CreateTodo(todo *model.Todo) *model.AppError {
-	todo, err := app.Repository.CreateTodo(todo)
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		if todo.ParentTodoID != nil {
+			if _, err := tran.GetTodo(*todo.ParentTodoID, todo.UserID); err != nil {
+				return err
+			}
+		}
+
+		position, err := topPosition(tran, todo.ParentTodoID, todo.UserID)
+		if err != nil {
+			return err
+		}
+
+		todo.Position = position
+		if _, err = tran.CreateTodo(todo); err != nil {
+			return err
+		}
+
+		return settleAutoCompletion(tran, todo.ParentTodoID, todo.UserID, changes)
+	})
+
 	if err != nil {
 		return err
 	}
 
+	app.publish(todo.UserID, model.NewTodoEvent(model.EVENT_TODO_CREATED, todo))
+	app.publishChanges(todo.UserID, changes, todo.ID)
 	return nil
 }
 
+// topPosition returns a position placing a todo before all of its siblings.
+// While the siblings are still unranked it is left empty as well, so the todo
+// is ranked along with them on their first reorder. Every prepend makes the
+// key longer, so once the first one passes TODO_POSITION_MAXIMUM_LENGTH the
+// siblings are ranked again to spread them out.
+func topPosition(tran *repository.Repository, parentId *int, userId int) (string, *model.AppError) {
+	first, err := tran.GetFirstTodoPosition(parentId, userId)
+	if err != nil || first == "" {
+		return "", err
+	}
+
+	if len(first) >= model.TODO_POSITION_MAXIMUM_LENGTH {
+		if err := tran.RankTodoSiblings(parentId, userId); err != nil {
+			return "", err
+		}
+
+		if first, err = tran.GetFirstTodoPosition(parentId, userId); err != nil {
+			return "", err
+		}
+	}
+
+	return util.RankBetween("", first), nil
+}
+
 func (app *App) GetTodo(todoId, userId int) (*model.Todo, *model.AppError) {
-	return app.Repository.GetTodo(todoId, userId)
+	todo, err := app.Repository.GetTodo(todoId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := app.Repository.GetTodoProgress([]int{todo.ID}, userId)
+	if err != nil {
+		return nil, err
+	}
+	todo.Progress = progress[todo.ID]
+
+	return todo, nil
+}
+
+// attachTodoProgress fills in the progress of each of the given todos.
+func (app *App) attachTodoProgress(todos []model.Todo, userId int) *model.AppError {
+	ids := make([]int, len(todos))
+	for i := range todos {
+		ids[i] = todos[i].ID
+	}
+
+	progress, err := app.Repository.GetTodoProgress(ids, userId)
+	if err != nil {
+		return err
+	}
+
+	for i := range todos {
+		todos[i].Progress = progress[todos[i].ID]
+	}
+
+	return nil
+}
+
+func (app *App) GetTodoAncestors(todoId, userId, depth int) ([]model.Todo, *model.AppError) {
+	if depth <= 0 {
+		return make([]model.Todo, 0), nil
+	}
+
+	if depth > model.TODO_ANCESTORS_MAXIMUM_DEPTH {
+		depth = model.TODO_ANCESTORS_MAXIMUM_DEPTH
+	}
+
+	return app.Repository.GetTodoAncestors(todoId, userId, depth)
+}
+
+func (app *App) GetTodoChildren(todoId, userId int, sort *model.TodoSort, page *model.TodoPage) ([]model.Todo, *string, *model.AppError) {
+	scopes, err := pageScopes(sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, err := app.Repository.GetTodoChildren(todoId, userId, sort, scopes...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, next := nextPage(todos, sort, page)
+	if err := app.attachTodoProgress(todos, userId); err != nil {
+		return nil, nil, err
+	}
+
+	return todos, next, nil
 }
 
-func (app *App) GetTodosBottomToTop(todoId, userId, depth int) ([]model.Todo, *model.AppError) {
-	var i int = 0
-	list := make([]model.Todo, 0)
-	id := todoId
+func (app *App) GetRootTodoChildren(userId int, sort *model.TodoSort, page *model.TodoPage) ([]model.Todo, *string, *model.AppError) {
+	scopes, err := pageScopes(sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, err := app.Repository.GetRootTodoChildren(userId, sort, scopes...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, next := nextPage(todos, sort, page)
+	if err := app.attachTodoProgress(todos, userId); err != nil {
+		return nil, nil, err
+	}
+
+	return todos, next, nil
+}
+
+// pageScopes returns the scopes fetching the given page plus one more todo,
+// which tells whether there is a next page at all.
+func pageScopes(sort *model.TodoSort, page *model.TodoPage) ([]func(*gorm.DB) *gorm.DB, *model.AppError) {
+	if sort == nil {
+		sort = model.DefaultTodoSort
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{repository.TodosLimit(page.Limit + 1)}
 
-	for i < depth {
-		head, err := app.Repository.GetTodo(id, userId)
+	if page.Cursor != "" {
+		cursor, err := model.ParseTodoCursor(page.Cursor, sort)
 		if err != nil {
 			return nil, err
 		}
+		scopes = append(scopes, repository.TodosAfter(cursor))
+	}
 
-		list = append(list, *head)
+	return scopes, nil
+}
 
-		if head.ParentTodoID == nil {
-			break
-		}
+// nextPage trims the extra todo fetched by pageScopes, returning the cursor to
+// the next page if there is one.
+func nextPage(todos []model.Todo, sort *model.TodoSort, page *model.TodoPage) ([]model.Todo, *string) {
+	if len(todos) <= page.Limit {
+		return todos, nil
+	}
+
+	if sort == nil {
+		sort = model.DefaultTodoSort
+	}
+
+	todos = todos[:page.Limit]
+	next := model.NewTodoCursor(sort, &todos[len(todos)-1]).Encode()
+
+	return todos, &next
+}
+
+func (app *App) GetTodoSubtree(todoId *int, userId, depth int, sort *model.TodoSort) ([]*model.TodoNode, *model.AppError) {
+	todos, err := app.Repository.GetTodoSubtree(todoId, userId, depth, model.TODO_SUBTREE_MAXIMUM_NODES+1, sort)
+	if err != nil {
+		return nil, err
+	}
 
-		id = *head.ParentTodoID
-		i += 1
+	if len(todos) > model.TODO_SUBTREE_MAXIMUM_NODES {
+		return nil, model.NewBadRequestError(fmt.Sprintf(model.MSG_TODO_SUBTREE_TOO_LARGE, model.TODO_SUBTREE_MAXIMUM_NODES))
 	}
 
-	return list, nil
+	if err := app.attachTodoProgress(todos, userId); err != nil {
+		return nil, err
+	}
+
+	return model.NewTodoTree(todoId, todos), nil
 }
 
-func (app *App) GetTodoChildren(todoId, userId int) ([]model.Todo, *model.AppError) {
-	return app.Repository.GetTodoChildren(todoId, userId)
+// GetFilteredTodos lists the todos matching the given filter across the whole
+// tree. Todos filtered by due date are sorted by it unless told otherwise.
+func (app *App) GetFilteredTodos(userId int, filter *model.TodoFilter, sort *model.TodoSort, page *model.TodoPage) ([]model.Todo, *string, *model.AppError) {
+	scopes := []func(*gorm.DB) *gorm.DB{}
+
+	if filter.Due != "" {
+		scope, err := dueScope(filter.Due, filter.Location, time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+		scopes = append(scopes, scope)
+
+		if sort == nil {
+			sort = &model.TodoSort{Column: "due_at", Descending: false}
+		}
+	}
+
+	if len(filter.Tags) > 0 {
+		scopes = append(scopes, repository.TodosWithTags(userId, filter.Tags))
+	}
+
+	if filter.Expression != nil {
+		scopes = append(scopes, repository.TodosMatching(userId, filter.Expression))
+	}
+
+	paging, err := pageScopes(sort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, err := app.Repository.GetTodos(userId, sort, append(scopes, paging...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	todos, next := nextPage(todos, sort, page)
+	if err := app.attachTodoProgress(todos, userId); err != nil {
+		return nil, nil, err
+	}
+
+	return todos, next, nil
 }
 
-func (app *App) GetRootTodoChildren(userId int) ([]model.Todo, *model.AppError) {
-	return app.Repository.GetRootTodoChildren(userId)
+// dueScope translates a due filter into a repository scope, where "today" and
+// "week" are calendar days in the given location.
+func dueScope(due string, loc *time.Location, now time.Time) (func(*gorm.DB) *gorm.DB, *model.AppError) {
+	year, month, day := now.In(loc).Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	switch due {
+	case model.TODO_DUE_OVERDUE:
+		return repository.TodosOverdue(now.UTC()), nil
+	case model.TODO_DUE_TODAY:
+		return repository.TodosDueBetween(today.UTC(), today.AddDate(0, 0, 1).UTC()), nil
+	case model.TODO_DUE_WEEK:
+		return repository.TodosDueBetween(today.UTC(), today.AddDate(0, 0, 7).UTC()), nil
+	default:
+		return nil, model.NewFormError(nil).AddError("due", model.MSG_TODO_DUE_INVALID)
+	}
 }
 
-func (app *App) UpdateTodo(todo *model.UpdateTodo, userId int) (*model.Todo, *model.AppError) {
-	dbTodo, err := app.GetTodo(todo.ID, userId)
+func (app *App) SearchTodos(query string, userId, limit int) ([]model.TodoSearchResult, *model.AppError) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, model.NewFormError(nil).AddError("q", model.MSG_TODO_SEARCH_MISSING)
+	}
+
+	todos, err := app.Repository.SearchTodos(terms, userId, limit)
 	if err != nil {
 		return nil, err
 	}
 
+	ids := make([]int, len(todos))
+	for i := range todos {
+		ids[i] = todos[i].ID
+	}
+
+	paths, err := app.Repository.GetTodoPaths(ids, userId, model.TODO_ANCESTORS_MAXIMUM_DEPTH)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.TodoSearchResult, len(todos))
+	for i := range todos {
+		results[i] = model.TodoSearchResult{Todo: todos[i], Path: paths[todos[i].ID]}
+	}
+
+	return results, nil
+}
+
+// UpdateTodo applies the given changes and returns how many todos had their
+// completion changed. With cascade, the completion of the todo is also
+// applied to all of its descendants.
+func (app *App) UpdateTodo(todo *model.UpdateTodo, userId int, cascade bool) (*model.Todo, int, *model.AppError) {
+	var dbTodo *model.Todo
+	affected := 0
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		dbTodo, affected, err = updateTodo(tran, todo, userId, cascade, changes)
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_UPDATED, dbTodo))
+	app.publishChanges(userId, changes, dbTodo.ID)
+	return dbTodo, affected, nil
+}
+
+// updateTodo is UpdateTodo within an existing transaction, collecting the
+// other todos it changes.
+func updateTodo(tran *repository.Repository, todo *model.UpdateTodo, userId int, cascade bool, changes *todoChanges) (*model.Todo, int, *model.AppError) {
+	dbTodo, err := tran.GetTodo(todo.ID, userId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := dbTodo.CheckVersion(todo.Version); err != nil {
+		return nil, 0, err
+	}
+
+	affected := 0
+	wasCompleted := dbTodo.Completed
+
 	if todo.Description != nil {
 		dbTodo.Description = *todo.Description
 	}
 
+	if todo.Notes != nil {
+		dbTodo.Notes = *todo.Notes
+	}
+
 	if todo.Completed != nil {
 		dbTodo.Completed = *todo.Completed
 	}
 
-	if err := app.Repository.UpdateTodo(dbTodo); err != nil {
+	enablesAutoComplete := todo.AutoComplete != nil && *todo.AutoComplete && !dbTodo.AutoComplete
+	if todo.AutoComplete != nil {
+		dbTodo.AutoComplete = *todo.AutoComplete
+	}
+
+	if todo.Priority != nil {
+		dbTodo.Priority = *todo.Priority
+	}
+
+	if todo.DueAt.Set {
+		dbTodo.DueAt = todo.DueAt.Time
+	}
+
+	if todo.RemindAt.Set {
+		dbTodo.RemindAt = todo.RemindAt.Time
+		dbTodo.RemindedAt = nil
+		dbTodo.ReminderClaimedAt = nil
+		dbTodo.ReminderAttempts = 0
+		dbTodo.ReminderRetryAt = nil
+	}
+
+	if todo.Recurrence.Set {
+		dbTodo.Recurrence = todo.Recurrence.Recurrence
+	}
+
+	// a todo only ever spawns its next occurrence once, however many times
+	// it is reopened and completed again
+	if !wasCompleted && dbTodo.Completed && dbTodo.Recurrence != nil && dbTodo.NextOccurrenceID == nil {
+		if err := createNextOccurrence(tran, dbTodo, changes); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := tran.UpdateTodo(dbTodo); err != nil {
+		return nil, 0, err
+	}
+
+	if wasCompleted != dbTodo.Completed {
+		affected++
+	}
+
+	if cascade {
+		ids, err := tran.SetTodoDescendantsCompleted(dbTodo.ID, userId, dbTodo.Completed)
+		if err != nil {
+			return nil, 0, err
+		}
+		affected += len(ids)
+		changes.update(ids...)
+	}
+
+	if enablesAutoComplete {
+		if err := settleAutoCompletion(tran, &dbTodo.ID, userId, changes); err != nil {
+			return nil, 0, err
+		}
+
+		if dbTodo, err = tran.GetTodo(dbTodo.ID, userId); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if wasCompleted != dbTodo.Completed {
+		if err := settleAutoCompletion(tran, dbTodo.ParentTodoID, userId, changes); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return dbTodo, affected, nil
+}
+
+// UpdateManyTodos applies the same changes to every given todo, all or
+// nothing, as long as each todo is still at the version given for it. It
+// returns the updated todos in the order their ids were given.
+func (app *App) UpdateManyTodos(update *model.UpdateManyTodos, userId int) ([]model.Todo, *model.AppError) {
+	todos := make([]model.Todo, len(update.IDs))
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		if err := checkTodoVersions(tran, update.IDs, update.Versions, userId); err != nil {
+			return err
+		}
+
+		var tags []model.Tag
+		if update.TagIDs != nil {
+			tags = make([]model.Tag, 0, len(*update.TagIDs))
+			for _, tagId := range *update.TagIDs {
+				tag, err := tran.GetTag(tagId, userId)
+				if err != nil {
+					return err
+				}
+				tags = append(tags, *tag)
+			}
+		}
+
+		// moved todos are put on top of their new siblings one at a time, so
+		// go backwards to keep them in the given order
+		for i := len(update.IDs) - 1; i >= 0; i-- {
+			todo, _, err := updateTodo(tran, &model.UpdateTodo{
+				ID:        update.IDs[i],
+				Completed: update.Completed,
+				Priority:  update.Priority,
+			}, userId, false, changes)
+			if err != nil {
+				return err
+			}
+
+			if update.ParentTodoID.Set {
+				if err := moveTodo(tran, todo, update.ParentTodoID.Value, userId, changes); err != nil {
+					return err
+				}
+			}
+
+			if update.TagIDs != nil {
+				if err := tran.SetTodoTags(todo, tags); err != nil {
+					return err
+				}
+			}
+		}
+
+		// reload them all since later updates may have completed earlier ones
+		for i, todoId := range update.IDs {
+			todo, err := tran.GetTodo(todoId, userId)
+			if err != nil {
+				return err
+			}
+			todos[i] = *todo
+		}
+
+		return nil
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
+	for i := range todos {
+		app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_UPDATED, &todos[i]))
+	}
+	app.publishChanges(userId, changes, update.IDs...)
+
+	return todos, nil
+}
+
+// createNextOccurrence creates the todo following a completed recurring one,
+// due on the next date of its rule and with its reminder shifted accordingly.
+// Todos without a due date recur from the moment they were completed. The new
+// todo is recorded on the completed one, which is left for the caller to save.
+func createNextOccurrence(tran *repository.Repository, todo *model.Todo, changes *todoChanges) *model.AppError {
+	from := time.Now().UTC()
+	if todo.DueAt != nil {
+		from = *todo.DueAt
+	}
+
+	dueAt, ok := todo.Recurrence.Next(from, todo.Occurrence)
+	if !ok {
+		return nil
+	}
+
+	next := &model.Todo{
+		ParentTodoID: todo.ParentTodoID,
+		UserID:       todo.UserID,
+		Description:  todo.Description,
+		Notes:        todo.Notes,
+		AutoComplete: todo.AutoComplete,
+		Priority:     todo.Priority,
+		DueAt:        &dueAt,
+		Recurrence:   todo.Recurrence,
+		Occurrence:   todo.Occurrence + 1,
+	}
+
+	if todo.RemindAt != nil {
+		remindAt := todo.RemindAt.Add(dueAt.Sub(from))
+		next.RemindAt = &remindAt
+	}
+
+	position, err := topPosition(tran, next.ParentTodoID, next.UserID)
+	if err != nil {
+		return err
+	}
+
+	next.Position = position
+	if _, err := tran.CreateTodo(next); err != nil {
+		return err
+	}
+
+	todo.NextOccurrenceID = &next.ID
+	changes.create(next.ID)
+
+	if len(todo.Tags) > 0 {
+		return tran.SetTodoTags(next, todo.Tags)
+	}
+
+	return nil
+}
+
+// settleAutoCompletion walks up from the given todo for as long as todos are
+// set to auto-complete, completing each one when all of its children are and
+// reopening it otherwise. It stops at the first todo left unchanged, and
+// collects the todos it changed.
+func settleAutoCompletion(tran *repository.Repository, todoId *int, userId int, changes *todoChanges) *model.AppError {
+	for todoId != nil {
+		todo, err := tran.GetTodo(*todoId, userId)
+		if err != nil {
+			return err
+		}
+
+		if !todo.AutoComplete {
+			return nil
+		}
+
+		progress, err := tran.GetTodoProgress([]int{todo.ID}, userId)
+		if err != nil {
+			return err
+		}
+
+		if progress[todo.ID].ChildCount == 0 || progress[todo.ID].AllChildrenCompleted() == todo.Completed {
+			return nil
+		}
+
+		todo.Completed = !todo.Completed
+		if err := tran.UpdateTodo(todo); err != nil {
+			return err
+		}
+		changes.update(todo.ID)
+
+		todoId = todo.ParentTodoID
+	}
+
+	return nil
+}
+
+// settleTodoParents settles the auto-completion of the parents of the given
+// todos, after they were deleted or brought back, leaving out the parents that
+// are among them.
+func settleTodoParents(tran *repository.Repository, todoIds []int, userId int, changes *todoChanges) *model.AppError {
+	todos, err := tran.GetTodosUnscoped(todoIds, userId)
+	if err != nil {
+		return err
+	}
+
+	settled := make(map[int]bool, len(todoIds))
+	for _, id := range todoIds {
+		settled[id] = true
+	}
+
+	for _, todo := range todos {
+		if todo.ParentTodoID == nil || settled[*todo.ParentTodoID] {
+			continue
+		}
+		settled[*todo.ParentTodoID] = true
+
+		if err := settleAutoCompletion(tran, todo.ParentTodoID, userId, changes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MoveTodo puts the todo on top of the children of its new parent, as long as
+// the todo is still at the given version, if any. It returns a token to move
+// it back.
+func (app *App) MoveTodo(move *model.MoveTodo, userId int) (*model.Todo, string, *model.AppError) {
+	var dbTodo *model.Todo
+	var token string
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		dbTodo, err = tran.GetTodo(move.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := dbTodo.CheckVersion(move.Version); err != nil {
+			return err
+		}
+
+		token, err = recordUndo(tran, userId, model.UNDO_MOVE, &model.UndoMove{
+			ID:           dbTodo.ID,
+			ParentTodoID: dbTodo.ParentTodoID,
+			Position:     dbTodo.Position,
+			MovedTo:      move.ParentTodoID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return moveTodo(tran, dbTodo, move.ParentTodoID, userId, changes)
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_UPDATED, dbTodo))
+	app.publishChanges(userId, changes, dbTodo.ID)
+	return dbTodo, token, nil
+}
+
+// DuplicateTodo deep copies a todo and all of its descendants, putting the
+// copy on top of its parent. It returns the copy and how many todos were
+// created.
+func (app *App) DuplicateTodo(duplicate *model.DuplicateTodo, userId int) (*model.Todo, int, *model.AppError) {
+	var copied *model.Todo
+	count := 0
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		todo, err := tran.GetTodo(duplicate.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		parentId := todo.ParentTodoID
+		if duplicate.ParentTodoID.Set {
+			parentId = duplicate.ParentTodoID.Value
+			if parentId != nil {
+				if _, err := tran.GetTodo(*parentId, userId); err != nil {
+					return err
+				}
+			}
+		}
+
+		root, err := loadTodoTree(tran, todo, userId)
+		if err != nil {
+			return err
+		}
+
+		if root.Position, err = topPosition(tran, parentId, userId); err != nil {
+			return err
+		}
+
+		if count, err = copyTodoNode(tran, root, parentId, duplicate.ResetCompleted, changes); err != nil {
+			return err
+		}
+
+		if err := settleAutoCompletion(tran, parentId, userId, changes); err != nil {
+			return err
+		}
+
+		copied, err = tran.GetTodo(root.ID, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_CREATED, copied))
+	app.publishChanges(userId, changes, copied.ID)
+	return copied, count, nil
+}
+
+// loadTodoTree loads the whole subtree of the given todo, tags included, as
+// long as it is within the subtree node cap. Siblings are kept oldest first,
+// so that unranked copies of them keep the same order.
+func loadTodoTree(tran *repository.Repository, todo *model.Todo, userId int) (*model.TodoNode, *model.AppError) {
+	subtree, err := tran.GetTodoSubtree(&todo.ID, userId, model.TODO_SUBTREE_MAXIMUM_NODES, model.TODO_SUBTREE_MAXIMUM_NODES, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(subtree)+1 > model.TODO_SUBTREE_MAXIMUM_NODES {
+		return nil, model.NewBadRequestError(fmt.Sprintf(model.MSG_TODO_SUBTREE_TOO_LARGE, model.TODO_SUBTREE_MAXIMUM_NODES))
+	}
+
+	// the subtree query does not load tags, so load them along with the todos
+	ids := make([]int, len(subtree))
+	for i := range subtree {
+		ids[i] = subtree[i].ID
+	}
+
+	descendants, err := tran.GetTodos(userId, &model.TodoSort{Column: "created_at"}, repository.TodosIn(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TodoNode{Todo: *todo, Children: model.NewTodoTree(&todo.ID, descendants)}, nil
+}
+
+// copyTodoNode creates a copy of the node under the given parent, followed by
+// copies of its children, returning how many todos were created. The ids of
+// the nodes are replaced by those of their copies.
+func copyTodoNode(tran *repository.Repository, node *model.TodoNode, parentId *int, resetCompleted bool, changes *todoChanges) (int, *model.AppError) {
+	todo := &model.Todo{
+		ParentTodoID: parentId,
+		UserID:       node.UserID,
+		Description:  node.Description,
+		Notes:        node.Notes,
+		Completed:    node.Completed && !resetCompleted,
+		AutoComplete: node.AutoComplete,
+		Priority:     node.Priority,
+		Position:     node.Position,
+		DueAt:        node.DueAt,
+		RemindAt:     node.RemindAt,
+		Recurrence:   node.Recurrence,
+		Occurrence:   node.Occurrence,
+	}
+
+	if _, err := tran.CreateTodo(todo); err != nil {
+		return 0, err
+	}
+
+	if len(node.Tags) > 0 {
+		if err := tran.SetTodoTags(todo, node.Tags); err != nil {
+			return 0, err
+		}
+	}
+
+	node.ID = todo.ID
+	changes.create(todo.ID)
+	count := 1
+	for _, child := range node.Children {
+		created, err := copyTodoNode(tran, child, &todo.ID, resetCompleted, changes)
+		if err != nil {
+			return 0, err
+		}
+		count += created
+	}
+
+	return count, nil
+}
+
+// moveTodo puts the todo on top of the children of the given parent, or of
+// the root todos when parentId is nil, settling both the parent it leaves and
+// the one it joins.
+func moveTodo(tran *repository.Repository, todo *model.Todo, parentId *int, userId int, changes *todoChanges) *model.AppError {
+	if err := checkTodoParent(tran, todo.ID, parentId, userId); err != nil {
+		return err
+	}
+
+	position, err := topPosition(tran, parentId, userId)
+	if err != nil {
+		return err
+	}
+
+	// ranking its siblings may have ranked the todo too, bumping its version
+	if sameTodoParent(todo.ParentTodoID, parentId) {
+		current, err := tran.GetTodo(todo.ID, userId)
+		if err != nil {
+			return err
+		}
+		todo.Version = current.Version
+	}
+
+	previousId := todo.ParentTodoID
+	todo.ParentTodoID = parentId
+	todo.Position = position
+
+	if err := tran.UpdateTodo(todo); err != nil {
+		return err
+	}
+
+	if err := settleAutoCompletion(tran, previousId, userId, changes); err != nil {
+		return err
+	}
+
+	return settleAutoCompletion(tran, parentId, userId, changes)
+}
+
+func sameTodoParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// checkTodoParent makes sure the given parent exists and is not the todo
+// itself or one of its descendants.
+func checkTodoParent(tran *repository.Repository, todoId int, parentId *int, userId int) *model.AppError {
+	if parentId == nil {
+		return nil
+	}
+
+	if _, err := tran.GetTodo(*parentId, userId); err != nil {
+		return err
+	}
+
+	isAncestor, err := tran.CheckIfTodoIsAncestor(todoId, *parentId, userId)
+	if err != nil {
+		return err
+	}
+
+	if isAncestor {
+		return model.NewFormError(nil).AddError("parentTodoId", model.MSG_TODO_MOVE_CYCLE)
+	}
+
+	return nil
+}
+
+// ReorderTodo places a todo between its siblings, giving it a position right
+// after reorder.After and/or right before reorder.Before so that only the
+// reordered todo has to be updated. Like MoveTodo, it checks the version of
+// the todo first, if one is given.
+func (app *App) ReorderTodo(reorder *model.ReorderTodo, userId int) (*model.Todo, *model.AppError) {
+	var dbTodo *model.Todo
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		dbTodo, err = tran.GetTodo(reorder.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := dbTodo.CheckVersion(reorder.Version); err != nil {
+			return err
+		}
+
+		unranked, err := tran.CheckIfTodoSiblingsUnranked(dbTodo.ParentTodoID, userId)
+		if err != nil {
+			return err
+		}
+
+		// ranking the siblings ranks the todo too, so load it again
+		if unranked {
+			if err := tran.RankTodoSiblings(dbTodo.ParentTodoID, userId); err != nil {
+				return err
+			}
+
+			if dbTodo, err = tran.GetTodo(reorder.ID, userId); err != nil {
+				return err
+			}
+		}
+
+		var low, high string
+		if reorder.After != nil {
+			if low, err = siblingPosition(tran, dbTodo, *reorder.After, userId); err != nil {
+				return err
+			}
+		}
+
+		if reorder.Before != nil {
+			if high, err = siblingPosition(tran, dbTodo, *reorder.Before, userId); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case reorder.Before == nil:
+			high, err = tran.GetAdjacentTodoPosition(dbTodo.ParentTodoID, userId, low, dbTodo.ID, false)
+		case reorder.After == nil:
+			low, err = tran.GetAdjacentTodoPosition(dbTodo.ParentTodoID, userId, high, dbTodo.ID, true)
+		case low >= high:
+			err = model.NewBadRequestError(fmt.Sprintf(model.MSG_TODO_REORDER_CONFLICT, *reorder.After, *reorder.Before))
+		}
+		if err != nil {
+			return err
+		}
+
+		dbTodo.Position = util.RankBetween(low, high)
+
+		return tran.UpdateTodo(dbTodo)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_UPDATED, dbTodo))
 	return dbTodo, nil
 }
 
-func (app *App) DeleteTodo(todoId, userId int) *model.AppError {
-	return app.Repository.DeleteTodo(todoId, userId)
+// siblingPosition returns the position of the given sibling of a todo, failing
+// if it is the todo itself or lives under another parent.
+func siblingPosition(tran *repository.Repository, todo *model.Todo, siblingId, userId int) (string, *model.AppError) {
+	sibling, err := tran.GetTodo(siblingId, userId)
+	if err != nil {
+		return "", err
+	}
+
+	if sibling.ID == todo.ID || !sibling.IsSiblingOf(todo) {
+		return "", model.NewBadRequestError(fmt.Sprintf(model.MSG_TODO_NOT_SIBLING, siblingId))
+	}
+
+	return sibling.Position, nil
 }
 
-func (app *App) DeleteManyTodos(todoIds []int, userId int) *model.AppError {
-	return app.Repository.DeleteManyTodos(todoIds, userId)
+// DeleteTodo moves the given todo and its subtree to the trash, as long as
+// the todo is still at the given version, if any. It returns a token to undo
+// it.
+func (app *App) DeleteTodo(todoId, userId int, version *int) (string, *model.AppError) {
+	return app.deleteTodos(userId, func(tran *repository.Repository) ([]int, *model.AppError) {
+		if version != nil {
+			todo, err := tran.GetTodo(todoId, userId)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := todo.CheckVersion(version); err != nil {
+				return nil, err
+			}
+		}
+
+		return tran.DeleteTodo(todoId, userId)
+	})
+}
+
+// DeleteManyTodos moves the given todos and their subtrees to the trash, as
+// long as each todo is still at the version given for it. It returns a token
+// to undo it.
+func (app *App) DeleteManyTodos(todoIds []int, versions map[int]int, userId int) (string, *model.AppError) {
+	return app.deleteTodos(userId, func(tran *repository.Repository) ([]int, *model.AppError) {
+		if err := checkTodoVersions(tran, todoIds, versions, userId); err != nil {
+			return nil, err
+		}
+
+		return tran.DeleteManyTodos(todoIds, userId)
+	})
+}
+
+// checkTodoVersions makes sure each of the todos is at the version given for
+// it, before any of them is changed so that changes to one do not count
+// against another.
+func checkTodoVersions(tran *repository.Repository, todoIds []int, versions map[int]int, userId int) *model.AppError {
+	for _, id := range todoIds {
+		version, ok := versions[id]
+		if !ok {
+			continue
+		}
+
+		todo, err := tran.GetTodo(id, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := todo.CheckVersion(&version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (app *App) deleteTodos(userId int, delete func(*repository.Repository) ([]int, *model.AppError)) (string, *model.AppError) {
+	var token string
+	var ids []int
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		ids, err = delete(tran)
+		if err != nil {
+			return err
+		}
+
+		if err := settleTodoParents(tran, ids, userId, changes); err != nil {
+			return err
+		}
+
+		token, err = recordUndo(tran, userId, model.UNDO_DELETE, &model.UndoDelete{IDs: ids})
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	app.publish(userId, model.NewTodoIDsEvent(model.EVENT_TODO_DELETED, ids))
+	app.publishChanges(userId, changes, ids...)
+	return token, nil
+}
+
+func (app *App) GetTrashedTodos(userId int) ([]model.Todo, *model.AppError) {
+	return app.Repository.GetTrashedTodos(userId)
+}
+
+// RestoreTodo brings a deleted todo back along with its deleted subtree,
+// returning the todo and how many todos were restored. A todo whose parent
+// is still deleted cannot be restored on its own.
+func (app *App) RestoreTodo(todoId, userId int) (*model.Todo, int, *model.AppError) {
+	var todo *model.Todo
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		trashed, err := tran.GetTrashedTodo(todoId, userId)
+		if err != nil {
+			return err
+		}
+
+		if trashed.ParentTodoID != nil {
+			parentTrashed, err := tran.CheckIfTodoTrashed(*trashed.ParentTodoID, userId)
+			if err != nil {
+				return err
+			}
+
+			if parentTrashed {
+				return model.NewConflictError(fmt.Sprintf(model.MSG_TODO_PARENT_TRASHED, todoId))
+			}
+		}
+
+		restored, err := tran.RestoreTodo(todoId, userId)
+		if err != nil {
+			return err
+		}
+		changes.create(restored...)
+
+		if err := settleAutoCompletion(tran, trashed.ParentTodoID, userId, changes); err != nil {
+			return err
+		}
+
+		todo, err = tran.GetTodo(todoId, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// todos brought back from the trash show up as created, as with undo
+	app.publish(userId, model.NewTodoEvent(model.EVENT_TODO_CREATED, todo))
+	app.publishChanges(userId, changes, todo.ID)
+	return todo, len(changes.created), nil
+}
+
+// PurgeTrash permanently deletes the todos that have been in the trash for
+// longer than the configured retention.
+func (app *App) PurgeTrash(now time.Time) *model.AppError {
+	retention := app.Config.Trash.Retention
+	if retention <= 0 {
+		retention = model.TRASH_RETENTION
+	}
+
+	_, err := app.Repository.PurgeTodos(now.AddDate(0, 0, -retention))
+	return err
 }
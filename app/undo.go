@@ -0,0 +1,170 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+	"github.com/jvitoroc/todo-go/util"
+)
+
+// recordUndo stores how to revert an operation done within the transaction,
+// returning the token to undo it with.
+func recordUndo(tran *repository.Repository, userId int, kind string, payload interface{}) (string, *model.AppError) {
+	now := time.Now()
+	if err := tran.DeleteExpiredUndoOperations(userId, now); err != nil {
+		return "", err
+	}
+
+	token, err := util.GenerateToken(model.UNDO_TOKEN_LENGTH)
+	if err != nil {
+		return "", model.NewGenericInternalError(err)
+	}
+
+	undo, err := model.NewUndoOperation(token, userId, kind, payload, now)
+	if err != nil {
+		return "", model.NewGenericInternalError(err)
+	}
+
+	if err := tran.CreateUndoOperation(undo); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Undo reverts the operation recorded under the given token, returning the
+// ids of the todos brought back. A token can only be used once.
+func (app *App) Undo(token string, userId int) ([]int, *model.AppError) {
+	var ids []int
+	var kind string
+	changes := &todoChanges{}
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		undo, err := tran.GetUndoOperation(token, userId)
+		if err != nil {
+			return err
+		}
+
+		if undo.ExpiresAt.Before(time.Now()) {
+			return model.NewNotFoundError(model.MSG_UNDO_NOT_FOUND)
+		}
+
+		claimed, err := tran.ClaimUndoOperation(token)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			return model.NewNotFoundError(model.MSG_UNDO_NOT_FOUND)
+		}
+
+		switch undo.Kind {
+		case model.UNDO_DELETE:
+			ids, err = undoDelete(tran, undo, userId, changes)
+			kind = model.EVENT_TODO_CREATED // like any other restore from the trash
+		case model.UNDO_MOVE:
+			ids, err = undoMove(tran, undo, userId, changes)
+			kind = model.EVENT_TODO_UPDATED
+		default:
+			err = model.NewInternalError(fmt.Sprintf("Unknown undo operation (%s).", undo.Kind))
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		app.publish(userId, model.NewTodoIDsEvent(kind, ids))
+	}
+	app.publishChanges(userId, changes, ids...)
+	return ids, nil
+}
+
+// undoDelete restores the deleted todos, unless one of them would end up
+// under a parent that has been deleted since, returning the ids of those that
+// were still in the trash.
+func undoDelete(tran *repository.Repository, undo *model.UndoOperation, userId int, changes *todoChanges) ([]int, *model.AppError) {
+	payload := &model.UndoDelete{}
+	if err := undo.DecodePayload(payload); err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	todos, err := tran.GetTodosUnscoped(payload.IDs, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[int]bool, len(payload.IDs))
+	for _, id := range payload.IDs {
+		deleted[id] = true
+	}
+
+	for _, todo := range todos {
+		if todo.ParentTodoID == nil || deleted[*todo.ParentTodoID] {
+			continue
+		}
+
+		parentTrashed, err := tran.CheckIfTodoTrashed(*todo.ParentTodoID, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		if parentTrashed {
+			return nil, model.NewConflictError(fmt.Sprintf(model.MSG_TODO_PARENT_TRASHED, todo.ID))
+		}
+	}
+
+	restored, err := tran.RestoreTodos(payload.IDs, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := settleTodoParents(tran, restored, userId, changes); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// undoMove puts the todo back under its previous parent, at its previous
+// position, unless it has been moved again since.
+func undoMove(tran *repository.Repository, undo *model.UndoOperation, userId int, changes *todoChanges) ([]int, *model.AppError) {
+	payload := &model.UndoMove{}
+	if err := undo.DecodePayload(payload); err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	todo, err := tran.GetTodo(payload.ID, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameTodoParent(todo.ParentTodoID, payload.MovedTo) {
+		return nil, model.NewConflictError(fmt.Sprintf(model.MSG_UNDO_MOVED, todo.ID))
+	}
+
+	if err := checkTodoParent(tran, todo.ID, payload.ParentTodoID, userId); err != nil {
+		return nil, err
+	}
+
+	previousId := todo.ParentTodoID
+	todo.ParentTodoID = payload.ParentTodoID
+	todo.Position = payload.Position
+	if err := tran.UpdateTodo(todo); err != nil {
+		return nil, err
+	}
+
+	if err := settleAutoCompletion(tran, previousId, userId, changes); err != nil {
+		return nil, err
+	}
+
+	if err := settleAutoCompletion(tran, payload.ParentTodoID, userId, changes); err != nil {
+		return nil, err
+	}
+
+	return []int{todo.ID}, nil
+}
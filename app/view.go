@@ -0,0 +1,93 @@
+package app
+
+import (
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+func (app *App) CreateView(view *model.View) *model.AppError {
+	_, err := app.Repository.CreateView(view)
+	return err
+}
+
+func (app *App) GetView(viewId, userId int) (*model.View, *model.AppError) {
+	return app.Repository.GetView(viewId, userId)
+}
+
+func (app *App) GetViews(userId int) ([]model.View, *model.AppError) {
+	return app.Repository.GetViews(userId)
+}
+
+// UpdateView applies the given changes and validates the view as a whole,
+// since its filter dates depend on its timezone.
+func (app *App) UpdateView(view *model.UpdateView, userId int) (*model.View, *model.AppError) {
+	var dbView *model.View
+	err := app.Repository.BeginTran(func(tran *repository.Repository) *model.AppError {
+		var err *model.AppError
+		dbView, err = tran.GetView(view.ID, userId)
+		if err != nil {
+			return err
+		}
+
+		if view.Name != nil {
+			dbView.Name = *view.Name
+		}
+
+		if view.Filter != nil {
+			dbView.Filter = *view.Filter
+		}
+
+		if view.Due != nil {
+			dbView.Due = *view.Due
+		}
+
+		if view.Sort != nil {
+			dbView.Sort = *view.Sort
+		}
+
+		if view.Order != nil {
+			dbView.Order = *view.Order
+		}
+
+		if view.Timezone != nil {
+			dbView.Timezone = *view.Timezone
+		}
+
+		if err := dbView.Validate(); err != nil {
+			return err
+		}
+
+		return tran.UpdateView(dbView)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dbView, nil
+}
+
+func (app *App) DeleteView(viewId, userId int) *model.AppError {
+	return app.Repository.DeleteView(viewId, userId)
+}
+
+// GetViewTodos lists the todos of the user matching the filter and sort the
+// view was saved with.
+func (app *App) GetViewTodos(viewId, userId int, page *model.TodoPage) ([]model.Todo, *string, *model.AppError) {
+	view, err := app.Repository.GetView(viewId, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, err := view.TodoFilter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort, err := view.TodoSort()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return app.GetFilteredTodos(userId, filter, sort, page)
+}
@@ -29,6 +29,13 @@ type Config struct {
 		JwtSecret      string `yaml:"jwtSecret"`
 		GoogleClientID string `yaml:"googleClientID"`
 	}
+	Reminder struct {
+		Interval int `yaml:"interval"` // seconds between checks for due reminders
+	}
+	Trash struct {
+		Retention int `yaml:"retention"` // days deleted todos are kept before being purged
+		Interval  int `yaml:"interval"`  // seconds between purges
+	}
 }
 
 func NewConfig(path string) *Config {
@@ -7,6 +7,10 @@ import (
 	"github.com/jvitoroc/todo-go/config"
 )
 
+type Sender interface {
+	SendEmail(to, subject, body string) error
+}
+
 type EmailService struct {
 	SmtpUser string
 	SmtpPass string
@@ -0,0 +1,92 @@
+package event
+
+import (
+	"sync"
+
+	"github.com/jvitoroc/todo-go/model"
+)
+
+// Hub fans todo events out to the subscriptions of each user. Publishing
+// never blocks: a subscription whose buffer is full is dropped and its
+// channel closed, so that a slow client cannot hold up the writers.
+type Hub struct {
+	mu            sync.Mutex
+	subscriptions map[int]map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	UserID int
+
+	events chan *model.TodoEvent
+}
+
+func NewHub() *Hub {
+	return &Hub{subscriptions: make(map[int]map[*Subscription]struct{})}
+}
+
+// Events delivers the events of the user, and is closed once the
+// subscription is dropped, cancelled or the hub is closed.
+func (s *Subscription) Events() <-chan *model.TodoEvent {
+	return s.events
+}
+
+func (h *Hub) Subscribe(userId int) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{UserID: userId, events: make(chan *model.TodoEvent, model.EVENT_BUFFER_SIZE)}
+	if h.subscriptions[userId] == nil {
+		h.subscriptions[userId] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userId][sub] = struct{}{}
+
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+// Publish hands the event to every subscription of the user.
+func (h *Hub) Publish(userId int, event *model.TodoEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscriptions[userId] {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription, letting open streams finish on shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.subscriptions {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// remove closes the channel of a subscription still in the hub, so that it
+// is closed exactly once. The lock must be held.
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.subscriptions[sub.UserID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscriptions, sub.UserID)
+	}
+	close(sub.events)
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jvitoroc/todo-go/util"
+)
+
+// Batch is an ordered list of todo operations run in a single transaction,
+// so that either all or none of them are applied.
+type Batch struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation creates, updates or deletes a todo. Body holds what the
+// equivalent request would have as body. A created todo can be given a
+// TempID, which later operations may use in place of its id. Like their
+// requests, updates and deletes must give the Version the todo is expected to
+// be at, and are only applied if it still is. Updates may give it in their
+// body instead.
+type BatchOperation struct {
+	Method       string          `json:"method"`
+	TempID       string          `json:"tempId"`
+	TodoID       *TodoRef        `json:"todoId"`
+	ParentTodoID *TodoRef        `json:"parentTodoId"`
+	Version      *int            `json:"version"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// BatchResult mirrors the response the operation would have had on its own.
+type BatchResult struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body"`
+}
+
+// TodoRef points to a todo either by its id or, when given as a string, by
+// the temporary id of a todo created earlier in the batch.
+type TodoRef struct {
+	ID     int
+	TempID string
+}
+
+func (ref *TodoRef) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &ref.TempID); err == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, &ref.ID)
+}
+
+// Resolve returns the id of the todo, looking temporary ids up in tempIds.
+func (ref *TodoRef) Resolve(key string, tempIds map[string]int) (int, *AppError) {
+	if ref.TempID == "" {
+		return ref.ID, nil
+	}
+
+	todoId, ok := tempIds[ref.TempID]
+	if !ok {
+		return 0, NewFormError(nil).AddError(key, fmt.Sprintf(MSG_BATCH_TEMP_ID_UNKNOWN, ref.TempID))
+	}
+
+	return todoId, nil
+}
+
+func BatchFromJson(data io.Reader) (*Batch, *AppError) {
+	batch := &Batch{}
+	if err := util.FromJson(data, batch); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return batch, nil
+}
+
+// NewBatchError reports that the operation at the given index failed, with
+// the status of the error it returned.
+func NewBatchError(index int, err *AppError) *AppError {
+	return &AppError{Code: err.Code, Message: fmt.Sprintf(MSG_BATCH_FAILED, index)}
+}
+
+func (batch *Batch) Validate() *AppError {
+	errors := map[string]string{}
+
+	if len(batch.Operations) == 0 {
+		errors["operations"] = MSG_BATCH_EMPTY
+	} else if len(batch.Operations) > BATCH_MAXIMUM_OPERATIONS {
+		errors["operations"] = fmt.Sprintf(MSG_BATCH_TOO_LARGE, BATCH_MAXIMUM_OPERATIONS)
+	}
+
+	tempIds := map[string]bool{}
+	for i, op := range batch.Operations {
+		key := fmt.Sprintf("operations.%d.", i)
+
+		switch op.Method {
+		case BATCH_CREATE:
+		case BATCH_UPDATE, BATCH_DELETE:
+			if op.TodoID == nil {
+				errors[key+"todoId"] = MSG_BATCH_TODO_MISSING
+			}
+		default:
+			errors[key+"method"] = MSG_BATCH_METHOD_INVALID
+		}
+
+		if op.TempID != "" {
+			if tempIds[op.TempID] {
+				errors[key+"tempId"] = fmt.Sprintf(MSG_BATCH_TEMP_ID_TAKEN, op.TempID)
+			}
+			tempIds[op.TempID] = true
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
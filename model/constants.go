@@ -9,18 +9,164 @@ const (
 	VERIFICATION_CODE_CHARS      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ123456789"
 
 	SESSION_EXPIRATION = 24 // maximum user session valid time in hours since its creation
+
+	TODO_SUBTREE_MAXIMUM_NODES   = 1000 // maximum number of todos returned in a single subtree
+	TODO_ANCESTORS_MAXIMUM_DEPTH = 100  // maximum number of parents returned for a single todo
+	TODO_POSITION_MAXIMUM_LENGTH = 16   // length of a position past which its siblings are ranked again
+
+	TODO_PAGE_DEFAULT_LIMIT = 100 // number of todos returned per page when no limit is given
+	TODO_PAGE_MAXIMUM_LIMIT = 500
+
+	TODO_SEARCH_DEFAULT_LIMIT = 20
+	TODO_SEARCH_MAXIMUM_LIMIT = 100
+
+	TODO_PRIORITY_MINIMUM = 0
+	TODO_PRIORITY_MAXIMUM = 3
+
+	TODO_DUE_OVERDUE = "overdue"
+	TODO_DUE_TODAY   = "today"
+	TODO_DUE_WEEK    = "week"
+
+	RECURRENCE_DAILY   = "daily"
+	RECURRENCE_WEEKLY  = "weekly"
+	RECURRENCE_MONTHLY = "monthly"
+
+	REMINDER_INTERVAL   = 60  // default time in seconds between checks for due reminders
+	REMINDER_BATCH_SIZE = 100 // maximum number of reminders sent on each check
+
+	REMINDER_MAX_ATTEMPTS  = 5   // number of failed sends after which a reminder is given up on
+	REMINDER_CLAIM_TIMEOUT = 600 // time in seconds after which a reminder claimed but never sent is claimed again
+	REMINDER_RETRY_DELAY   = 60  // time in seconds before the first retry of a failed reminder, doubled on every retry
+
+	TRASH_RETENTION      = 30   // default time in days deleted todos are kept before being purged
+	TRASH_PURGE_INTERVAL = 3600 // default time in seconds between purges of the trash
+
+	UNDO_EXPIRATION   = 60 // maximum time in seconds to undo an operation after it was done
+	UNDO_TOKEN_LENGTH = 16 // number of random bytes in an undo token
+
+	UNDO_DELETE = "delete"
+	UNDO_MOVE   = "move"
+
+	BATCH_MAXIMUM_OPERATIONS = 100
+
+	EVENT_BUFFER_SIZE = 64 // number of events kept for a client before it is dropped for falling behind
+	EVENT_HEARTBEAT   = 30 // time in seconds between comments keeping idle event streams open
+
+	EVENT_TODO_CREATED = "todo.created"
+	EVENT_TODO_UPDATED = "todo.updated"
+	EVENT_TODO_DELETED = "todo.deleted"
+	EVENT_STREAM_RESET = "reset" // sent before ending a stream, telling the client to sync before reconnecting
+
+	SYNC_DEFAULT_LIMIT = 500 // number of changed todos returned per sync when no limit is given
+	SYNC_MAXIMUM_LIMIT = 1000
+
+	BATCH_CREATE = "create"
+	BATCH_UPDATE = "update"
+	BATCH_DELETE = "delete"
 )
 
 const (
-	MSG_TODO_CREATED   = "The todo was successfully created."
-	MSG_TODO_RETRIEVED = "The todo was successfully retrieved."
-	MSG_TODO_UPDATED   = "The todo was successfully updated."
-	MSG_TODO_DELETED   = "The todo was successfully deleted."
-	MSG_TODOS_DELETED  = "The todos were successfully deleted."
-
-	MSG_TODO_NOT_FOUND           = "Todo not found under given id (%d)."
-	MSG_TODO_DESCRIPTION_MISSING = "Description field is empty or missing."
-	MSG_TODO_IDS_NOT_PROVIDED    = "List of todo ids not provided."
+	MSG_TODO_CREATED    = "The todo was successfully created."
+	MSG_TODO_RETRIEVED  = "The todo was successfully retrieved."
+	MSG_TODO_UPDATED    = "The todo was successfully updated."
+	MSG_TODO_MOVED      = "The todo was successfully moved."
+	MSG_TODO_REORDERED  = "The todo was successfully reordered."
+	MSG_TODO_DELETED    = "The todo was successfully deleted."
+	MSG_TODO_RESTORED   = "The todo was successfully restored."
+	MSG_TODO_DUPLICATED = "The todo was successfully duplicated."
+	MSG_TODOS_UPDATED   = "The todos were successfully updated."
+	MSG_TODOS_DELETED   = "The todos were successfully deleted."
+
+	MSG_TODO_NOT_FOUND            = "Todo not found under given id (%d)."
+	MSG_TODO_DESCRIPTION_MISSING  = "Description field is empty or missing."
+	MSG_TODO_IDS_NOT_PROVIDED     = "List of todo ids not provided."
+	MSG_TODO_MOVE_CYCLE           = "A todo cannot be moved under itself or one of its descendants."
+	MSG_TODO_SUBTREE_TOO_LARGE    = "The requested subtree has more than %d todos, try a smaller depth."
+	MSG_TODO_DUE_INVALID          = "Due filter must be one of overdue, today or week."
+	MSG_TIMEZONE_INVALID          = "Unknown timezone (%s)."
+	MSG_TODO_PRIORITY_INVALID     = "Priority must be between %d and %d."
+	MSG_TODO_SORT_INVALID         = "Sort must be one of created, updated, due, priority, position or description."
+	MSG_TODO_ORDER_INVALID        = "Order must be either asc or desc."
+	MSG_TODO_ORDER_WITHOUT_SORT   = "Order can only be given along with sort."
+	MSG_TODO_LIMIT_INVALID        = "Limit must be between 1 and %d."
+	MSG_TODO_CURSOR_INVALID       = "Cursor is invalid."
+	MSG_TODO_CURSOR_SORT_MISMATCH = "Cursor was created for a different sort."
+	MSG_TODO_SEARCH_MISSING       = "Search query is empty or missing."
+	MSG_TODO_REORDER_MISSING      = "Either before or after must be given."
+	MSG_TODO_NOT_SIBLING          = "Todo (%d) is not a sibling of the reordered todo."
+	MSG_TODO_REORDER_CONFLICT     = "Todo (%d) does not come before todo (%d)."
+	MSG_TODO_NOT_IN_TRASH         = "Todo not found in trash under given id (%d)."
+	MSG_TODO_PARENT_TRASHED       = "The parent of todo (%d) is deleted, restore it first."
+	MSG_TODO_CASCADE_INVALID      = "Cascade can only be used when changing completed."
+	MSG_TODO_VERSION_MISSING      = "Either an If-Match header or the version of the todo must be given."
+	MSG_TODO_VERSION_INVALID      = "If-Match must hold ETags of the todo."
+	MSG_TODO_VERSION_NO_MATCH     = "The todo is at none of the versions given in If-Match, its current state is attached."
+	MSG_TODO_VERSIONS_MISSING     = "The version of todo (%d) must be given in versions."
+	MSG_TODO_VERSION_MISMATCH     = "The todo is no longer at version %d, its current state is attached."
+	MSG_TODO_VERSION_CHANGED      = "The todo changed from version %d while being updated, fetch it and try again."
+
+	MSG_FILTER_INVALID            = "Filter is invalid."
+	MSG_FILTER_EMPTY              = "Filter is empty."
+	MSG_FILTER_UNTERMINATED       = "Quote at position %d is never closed."
+	MSG_FILTER_UNEXPECTED_END     = "Filter ends unexpectedly."
+	MSG_FILTER_UNEXPECTED_TOKEN   = "Unexpected %q at position %d."
+	MSG_FILTER_COMPARISON_INVALID = "Expected a comparison like field:value instead of %q at position %d."
+	MSG_FILTER_FIELD_INVALID      = "Unknown field in %q at position %d, use one of completed, priority, due, created, updated, description, notes or tag."
+	MSG_FILTER_VALUE_INVALID      = "Invalid value or comparison in %q at position %d."
+
+	MSG_RECURRENCE_FREQUENCY_INVALID = "Frequency must be one of daily, weekly or monthly."
+	MSG_RECURRENCE_INTERVAL_INVALID  = "Interval must be 1 or more."
+	MSG_RECURRENCE_COUNT_INVALID     = "Count must be 1 or more."
+	MSG_RECURRENCE_UNTIL_AND_COUNT   = "Until and count cannot be used together."
+	MSG_RECURRENCE_WEEKDAY_INVALID   = "Unknown weekday (%s), use one of MO, TU, WE, TH, FR, SA or SU."
+	MSG_RECURRENCE_BY_WEEKDAY_WEEKLY = "Weekdays can only be used with weekly recurrences."
+
+	MSG_TAG_CREATED        = "The tag was successfully created."
+	MSG_TAG_RETRIEVED      = "The tag was successfully retrieved."
+	MSG_TAGS_RETRIEVED     = "The tags were successfully retrieved."
+	MSG_TAG_UPDATED        = "The tag was successfully updated."
+	MSG_TAG_DELETED        = "The tag was successfully deleted."
+	MSG_TODO_TAG_ADDED     = "The tag was successfully added to the todo."
+	MSG_TODO_TAG_REMOVED   = "The tag was successfully removed from the todo."
+	MSG_TAG_NOT_FOUND      = "Tag not found under given id (%d)."
+	MSG_TAG_NAME_MISSING   = "Name field is empty or missing."
+	MSG_TAG_COLOR_INVALID  = "Color must be a hex color like #1a2b3c."
+	MSG_TAG_ALREADY_EXISTS = "Tag name already exists."
+
+	MSG_UNDONE         = "The operation was successfully undone."
+	MSG_UNDO_NOT_FOUND = "Undo token not found or expired."
+	MSG_UNDO_MOVED     = "Todo (%d) has been moved again since, the move cannot be undone."
+
+	MSG_BATCH_DONE            = "The operations were successfully applied."
+	MSG_BATCH_FAILED          = "Operation %d failed, none of the operations were applied."
+	MSG_BATCH_EMPTY           = "List of operations not provided."
+	MSG_BATCH_TOO_LARGE       = "A batch can have at most %d operations."
+	MSG_BATCH_METHOD_INVALID  = "Method must be one of create, update or delete."
+	MSG_BATCH_TODO_MISSING    = "TodoId field is empty or missing."
+	MSG_BATCH_TEMP_ID_TAKEN   = "Temporary id (%s) is already used."
+	MSG_BATCH_TEMP_ID_UNKNOWN = "Unknown temporary id (%s)."
+
+	MSG_EVENTS_UNSUPPORTED = "Event streams are not supported by this connection."
+
+	MSG_SYNC_RETRIEVED      = "The changes were successfully retrieved."
+	MSG_SYNC_CURSOR_INVALID = "Since must be a cursor returned by a previous sync."
+
+	MSG_VIEW_CREATED      = "The view was successfully created."
+	MSG_VIEW_RETRIEVED    = "The view was successfully retrieved."
+	MSG_VIEWS_RETRIEVED   = "The views were successfully retrieved."
+	MSG_VIEW_UPDATED      = "The view was successfully updated."
+	MSG_VIEW_DELETED      = "The view was successfully deleted."
+	MSG_VIEW_NOT_FOUND    = "View not found under given id (%d)."
+	MSG_VIEW_NAME_MISSING = "Name field is empty or missing."
+
+	MSG_TEMPLATE_CREATED      = "The template was successfully created."
+	MSG_TEMPLATE_RETRIEVED    = "The template was successfully retrieved."
+	MSG_TEMPLATES_RETRIEVED   = "The templates were successfully retrieved."
+	MSG_TEMPLATE_DELETED      = "The template was successfully deleted."
+	MSG_TEMPLATE_INSTANTIATED = "The template was successfully instantiated."
+	MSG_TEMPLATE_NOT_FOUND    = "Template not found under given id (%d)."
+	MSG_TEMPLATE_TODO_MISSING = "TodoId field is empty or missing."
+	MSG_TEMPLATE_NAME_MISSING = "Name field is empty or missing."
 
 	MSG_SESSION_CREATED    = "The session was successfully created."
 	MSG_INVALID_CREDENTIAL = "Invalid username or password."
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type TodoPage struct {
+	Limit  int
+	Cursor string
+}
+
+// TodoCursor points right after the last todo of a page, holding its value
+// for the sorted column and its id to break ties. It is handed to clients as
+// an opaque string.
+type TodoCursor struct {
+	Column     string          `json:"c"`
+	Descending bool            `json:"d"`
+	Value      json.RawMessage `json:"v"`
+	ID         int             `json:"i"`
+}
+
+func NewTodoPage(limit int, cursor string) *TodoPage {
+	if limit == 0 {
+		limit = TODO_PAGE_DEFAULT_LIMIT
+	}
+
+	return &TodoPage{Limit: limit, Cursor: cursor}
+}
+
+func (page *TodoPage) Validate() *AppError {
+	errors := map[string]string{}
+
+	if page.Limit < 1 || page.Limit > TODO_PAGE_MAXIMUM_LIMIT {
+		errors["limit"] = fmt.Sprintf(MSG_TODO_LIMIT_INVALID, TODO_PAGE_MAXIMUM_LIMIT)
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
+
+func NewTodoCursor(sort *TodoSort, todo *Todo) *TodoCursor {
+	var value interface{}
+	switch sort.Column {
+	case "created_at":
+		value = todo.CreatedAt
+	case "updated_at":
+		value = todo.UpdatedAt
+	case "due_at":
+		value = todo.DueAt
+	case "priority":
+		value = todo.Priority
+	case "position":
+		value = todo.Position
+	case "description":
+		value = todo.Description
+	}
+
+	data, _ := json.Marshal(value)
+	return &TodoCursor{Column: sort.Column, Descending: sort.Descending, Value: data, ID: todo.ID}
+}
+
+// ParseTodoCursor decodes a cursor given by a client, which is only valid for
+// the same sort it was created with.
+func ParseTodoCursor(cursor string, sort *TodoSort) (*TodoCursor, *AppError) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, NewFormError(nil).AddError("cursor", MSG_TODO_CURSOR_INVALID)
+	}
+
+	parsed := &TodoCursor{}
+	if err := json.Unmarshal(data, parsed); err != nil {
+		return nil, NewFormError(nil).AddError("cursor", MSG_TODO_CURSOR_INVALID)
+	}
+
+	if parsed.Column != sort.Column || parsed.Descending != sort.Descending {
+		return nil, NewFormError(nil).AddError("cursor", MSG_TODO_CURSOR_SORT_MISMATCH)
+	}
+
+	if _, err := parsed.TypedValue(); err != nil {
+		return nil, NewFormError(nil).AddError("cursor", MSG_TODO_CURSOR_INVALID)
+	}
+
+	return parsed, nil
+}
+
+func (cursor *TodoCursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// TypedValue decodes the cursor value into the type of its column, so it is
+// compared by the database the same way as the stored values. It is nil when
+// the last todo had no value for the column.
+func (cursor *TodoCursor) TypedValue() (interface{}, error) {
+	if string(cursor.Value) == "null" {
+		return nil, nil
+	}
+
+	var err error
+	switch cursor.Column {
+	case "created_at", "updated_at", "due_at":
+		var value time.Time
+		err = json.Unmarshal(cursor.Value, &value)
+		return value, err
+	case "priority":
+		var value int
+		err = json.Unmarshal(cursor.Value, &value)
+		return value, err
+	default:
+		var value string
+		err = json.Unmarshal(cursor.Value, &value)
+		return value, err
+	}
+}
@@ -14,12 +14,18 @@ type AppError struct {
 	Errors map[string]string `json:"errors,omitempty"`
 
 	Data map[string]interface{} `json:"data,omitempty"`
+
+	Headers http.Header `json:"-"`
 }
 
 func (err *AppError) GetCode() int {
 	return err.Code
 }
 
+func (err *AppError) GetHeaders() http.Header {
+	return err.Headers
+}
+
 func (err *AppError) ToJson() string {
 	json, _ := json.Marshal(err)
 	return string(json)
@@ -40,6 +46,16 @@ func (err *AppError) AddObject(key string, value interface{}) *AppError {
 	return err
 }
 
+func (err *AppError) SetHeader(key, value string) *AppError {
+	if err.Headers == nil {
+		err.Headers = make(http.Header)
+	}
+
+	err.Headers.Set(key, value)
+
+	return err
+}
+
 func (err *AppError) AddError(key, value string) *AppError {
 	if err.Errors == nil {
 		err.Errors = make(map[string]string)
@@ -82,6 +98,14 @@ func NewNotFoundError(message string) *AppError {
 	return &AppError{Code: http.StatusNotFound, Message: message}
 }
 
+func NewPreconditionFailedError(message string) *AppError {
+	return &AppError{Code: http.StatusPreconditionFailed, Message: message}
+}
+
+func NewPreconditionRequiredError(message string) *AppError {
+	return &AppError{Code: http.StatusPreconditionRequired, Message: message}
+}
+
 func NewFormError(errors map[string]string) *AppError {
 	return &AppError{Code: http.StatusBadRequest, Message: MSG_ERR_SEVERAL, Errors: errors}
 }
@@ -0,0 +1,19 @@
+package model
+
+// TodoEvent tells the clients of a user that some of their todos changed.
+// Events about a single todo carry it, while the others only list the ids
+// of the todos involved, for clients to fetch them again or sync. Todos
+// brought back from the trash are announced as created.
+type TodoEvent struct {
+	Type    string `json:"type"`
+	TodoIDs []int  `json:"todoIds"`
+	Todo    *Todo  `json:"todo,omitempty"`
+}
+
+func NewTodoEvent(kind string, todo *Todo) *TodoEvent {
+	return &TodoEvent{Type: kind, TodoIDs: []int{todo.ID}, Todo: todo}
+}
+
+func NewTodoIDsEvent(kind string, todoIds []int) *TodoEvent {
+	return &TodoEvent{Type: kind, TodoIDs: todoIds}
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	FILTER_AND = "and"
+	FILTER_OR  = "or"
+	FILTER_NOT = "not"
+	FILTER_CMP = "cmp"
+
+	FILTER_BOOL = "bool"
+	FILTER_INT  = "int"
+	FILTER_DATE = "date"
+	FILTER_TEXT = "text"
+	FILTER_TAG  = "tag"
+)
+
+// filterFields whitelists the fields a filter can refer to, along with their
+// column and type.
+var filterFields = map[string]struct {
+	column string
+	kind   string
+}{
+	"completed":   {"completed", FILTER_BOOL},
+	"priority":    {"priority", FILTER_INT},
+	"due":         {"due_at", FILTER_DATE},
+	"created":     {"created_at", FILTER_DATE},
+	"updated":     {"updated_at", FILTER_DATE},
+	"description": {"description", FILTER_TEXT},
+	"notes":       {"notes", FILTER_TEXT},
+	"tag":         {"tag", FILTER_TAG},
+}
+
+var filterComparators = []string{"<=", ">=", "!=", ":", "<", ">"}
+
+// FilterNode is a node of a parsed filter. Boolean nodes hold their operands
+// in Children, while comparisons hold a whitelisted Column, a Comparator and
+// a typed Value, which is nil when comparing against "none".
+type FilterNode struct {
+	Op       string
+	Children []*FilterNode
+
+	Column     string
+	Kind       string
+	Comparator string
+	Value      interface{}
+}
+
+type filterToken struct {
+	text     string
+	position int
+}
+
+type filterParser struct {
+	tokens   []filterToken
+	current  int
+	location *time.Location
+}
+
+// ParseTodoFilter parses expressions like
+//
+//	completed:false AND (tag:work OR due<2026-11-01)
+//
+// where dates without a time are whole days in the given location.
+func ParseTodoFilter(input string, loc *time.Location) (*FilterNode, *AppError) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, NewBadRequestError(MSG_FILTER_INVALID).SetDetail(MSG_FILTER_EMPTY)
+	}
+
+	parser := &filterParser{tokens: tokens, location: loc}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.current < len(parser.tokens) {
+		return nil, parser.unexpected()
+	}
+
+	return node, nil
+}
+
+func tokenizeFilter(input string) ([]filterToken, *AppError) {
+	tokens := []filterToken{}
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '(' || runes[i] == ')':
+			tokens = append(tokens, filterToken{string(runes[i]), i})
+			i++
+		default:
+			start := i
+			quoted := false
+			for i < len(runes) && (quoted || !(unicode.IsSpace(runes[i]) || runes[i] == '(' || runes[i] == ')')) {
+				if runes[i] == '"' {
+					quoted = !quoted
+				}
+				i++
+			}
+
+			if quoted {
+				return nil, NewBadRequestError(MSG_FILTER_INVALID).SetDetail(fmt.Sprintf(MSG_FILTER_UNTERMINATED, start))
+			}
+
+			tokens = append(tokens, filterToken{string(runes[start:i]), start})
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.current >= len(p.tokens) {
+		return ""
+	}
+
+	return p.tokens[p.current].text
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	return strings.EqualFold(p.peek(), keyword)
+}
+
+func (p *filterParser) unexpected() *AppError {
+	if p.current >= len(p.tokens) {
+		return NewBadRequestError(MSG_FILTER_INVALID).SetDetail(MSG_FILTER_UNEXPECTED_END)
+	}
+
+	token := p.tokens[p.current]
+	return NewBadRequestError(MSG_FILTER_INVALID).SetDetail(fmt.Sprintf(MSG_FILTER_UNEXPECTED_TOKEN, token.text, token.position))
+}
+
+func (p *filterParser) invalid(token filterToken, message string) *AppError {
+	return NewBadRequestError(MSG_FILTER_INVALID).SetDetail(fmt.Sprintf(message, token.text, token.position))
+}
+
+func (p *filterParser) parseOr() (*FilterNode, *AppError) {
+	return p.parseBinary(FILTER_OR, p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*FilterNode, *AppError) {
+	return p.parseBinary(FILTER_AND, p.parseUnary)
+}
+
+func (p *filterParser) parseBinary(op string, operand func() (*FilterNode, *AppError)) (*FilterNode, *AppError) {
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []*FilterNode{node}
+	for p.isKeyword(op) {
+		p.current++
+
+		node, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+
+	return &FilterNode{Op: op, Children: children}, nil
+}
+
+func (p *filterParser) parseUnary() (*FilterNode, *AppError) {
+	if p.isKeyword(FILTER_NOT) {
+		p.current++
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &FilterNode{Op: FILTER_NOT, Children: []*FilterNode{node}}, nil
+	}
+
+	if p.peek() == "(" {
+		p.current++
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, p.unexpected()
+		}
+		p.current++
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (*FilterNode, *AppError) {
+	if p.current >= len(p.tokens) || p.peek() == ")" || p.isKeyword(FILTER_AND) || p.isKeyword(FILTER_OR) {
+		return nil, p.unexpected()
+	}
+
+	token := p.tokens[p.current]
+	p.current++
+
+	name := strings.IndexFunc(token.text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if name <= 0 {
+		return nil, p.invalid(token, MSG_FILTER_COMPARISON_INVALID)
+	}
+
+	field, ok := filterFields[strings.ToLower(token.text[:name])]
+	if !ok {
+		return nil, p.invalid(token, MSG_FILTER_FIELD_INVALID)
+	}
+
+	comparator := ""
+	for _, candidate := range filterComparators {
+		if strings.HasPrefix(token.text[name:], candidate) {
+			comparator = candidate
+			break
+		}
+	}
+
+	if comparator == "" {
+		return nil, p.invalid(token, MSG_FILTER_COMPARISON_INVALID)
+	}
+
+	raw := strings.Trim(token.text[name+len(comparator):], `"`)
+	ordered := comparator != ":" && comparator != "!="
+
+	switch field.kind {
+	case FILTER_BOOL:
+		value, err := strconv.ParseBool(raw)
+		if err != nil || ordered {
+			return nil, p.invalid(token, MSG_FILTER_VALUE_INVALID)
+		}
+		return &FilterNode{Op: FILTER_CMP, Column: field.column, Kind: field.kind, Comparator: comparator, Value: value}, nil
+	case FILTER_INT:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, p.invalid(token, MSG_FILTER_VALUE_INVALID)
+		}
+		return &FilterNode{Op: FILTER_CMP, Column: field.column, Kind: field.kind, Comparator: comparator, Value: value}, nil
+	case FILTER_TEXT, FILTER_TAG:
+		if raw == "" || ordered {
+			return nil, p.invalid(token, MSG_FILTER_VALUE_INVALID)
+		}
+		return &FilterNode{Op: FILTER_CMP, Column: field.column, Kind: field.kind, Comparator: comparator, Value: raw}, nil
+	default:
+		return p.parseDate(token, field.column, comparator, raw)
+	}
+}
+
+// parseDate turns a comparison against a date into one against the bounds of
+// that day, so that due:2026-11-01 matches anything due during that day.
+func (p *filterParser) parseDate(token filterToken, column, comparator, raw string) (*FilterNode, *AppError) {
+	cmp := func(comparator string, value interface{}) *FilterNode {
+		return &FilterNode{Op: FILTER_CMP, Column: column, Kind: FILTER_DATE, Comparator: comparator, Value: value}
+	}
+
+	if strings.EqualFold(raw, "none") {
+		if comparator != ":" && comparator != "!=" {
+			return nil, p.invalid(token, MSG_FILTER_VALUE_INVALID)
+		}
+		return cmp(comparator, nil), nil
+	}
+
+	if value, err := time.Parse(time.RFC3339, raw); err == nil {
+		return cmp(comparator, value), nil
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", raw, p.location)
+	if err != nil {
+		return nil, p.invalid(token, MSG_FILTER_VALUE_INVALID)
+	}
+	end := start.AddDate(0, 0, 1)
+
+	switch comparator {
+	case ":":
+		return &FilterNode{Op: FILTER_AND, Children: []*FilterNode{cmp(">=", start), cmp("<", end)}}, nil
+	case "!=":
+		return &FilterNode{Op: FILTER_OR, Children: []*FilterNode{cmp("<", start), cmp(">=", end)}}, nil
+	case "<=":
+		return cmp("<", end), nil
+	case ">":
+		return cmp(">=", end), nil
+	default:
+		return cmp(comparator, start), nil
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OptionalTime tells apart a time field missing from a request body (Set is
+// false) from one explicitly set to null (Set is true and Time is nil).
+type OptionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (o *OptionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Time = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	t = t.UTC()
+	o.Time = &t
+	return nil
+}
+
+func (o OptionalTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Time)
+}
+
+// OptionalRecurrence works like OptionalTime for recurrence rules.
+type OptionalRecurrence struct {
+	Set        bool
+	Recurrence *Recurrence
+}
+
+func (o *OptionalRecurrence) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Recurrence = nil
+		return nil
+	}
+
+	o.Recurrence = &Recurrence{}
+	return json.Unmarshal(data, o.Recurrence)
+}
+
+func (o OptionalRecurrence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Recurrence)
+}
+
+// OptionalInt works like OptionalTime for ids, where null usually stands for
+// the root of the tree.
+type OptionalInt struct {
+	Set   bool
+	Value *int
+}
+
+func (o *OptionalInt) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	o.Value = &value
+	return nil
+}
+
+func (o OptionalInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Value)
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Recurrence struct {
+	Frequency string     `json:"frequency"`
+	Interval  int        `json:"interval"`
+	ByWeekday []string   `json:"byWeekday,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	Count     *int       `json:"count,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func (rec Recurrence) Value() (driver.Value, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (rec *Recurrence) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(data), rec)
+	case []byte:
+		return json.Unmarshal(data, rec)
+	default:
+		return fmt.Errorf("Unsupported recurrence value type: %T", value)
+	}
+}
+
+// normalize fills in the defaults of an RRULE, where a missing interval means
+// every occurrence, and stores the until date in UTC.
+func (rec *Recurrence) normalize() {
+	if rec.Interval == 0 {
+		rec.Interval = 1
+	}
+
+	if rec.Until != nil {
+		until := rec.Until.UTC()
+		rec.Until = &until
+	}
+}
+
+func (rec *Recurrence) Validate(errors map[string]string) {
+	switch rec.Frequency {
+	case RECURRENCE_DAILY, RECURRENCE_MONTHLY:
+		if len(rec.ByWeekday) > 0 {
+			errors["recurrence.byWeekday"] = MSG_RECURRENCE_BY_WEEKDAY_WEEKLY
+		}
+	case RECURRENCE_WEEKLY:
+		for _, day := range rec.ByWeekday {
+			if _, ok := weekdays[day]; !ok {
+				errors["recurrence.byWeekday"] = fmt.Sprintf(MSG_RECURRENCE_WEEKDAY_INVALID, day)
+				break
+			}
+		}
+	default:
+		errors["recurrence.frequency"] = MSG_RECURRENCE_FREQUENCY_INVALID
+	}
+
+	if rec.Interval < 1 {
+		errors["recurrence.interval"] = MSG_RECURRENCE_INTERVAL_INVALID
+	}
+
+	if rec.Count != nil && *rec.Count < 1 {
+		errors["recurrence.count"] = MSG_RECURRENCE_COUNT_INVALID
+	}
+
+	if rec.Count != nil && rec.Until != nil {
+		errors["recurrence.until"] = MSG_RECURRENCE_UNTIL_AND_COUNT
+	}
+}
+
+// Next returns the occurrence following the given one, or false when the
+// rule has run out of occurrences.
+func (rec *Recurrence) Next(from time.Time, occurrence int) (time.Time, bool) {
+	if rec.Count != nil && occurrence >= *rec.Count {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	switch rec.Frequency {
+	case RECURRENCE_DAILY:
+		next = from.AddDate(0, 0, rec.Interval)
+	case RECURRENCE_WEEKLY:
+		next = rec.nextWeekday(from)
+	case RECURRENCE_MONTHLY:
+		next = addMonths(from, rec.Interval)
+	}
+
+	if rec.Until != nil && next.After(*rec.Until) {
+		return time.Time{}, false
+	}
+
+	return next, true
+}
+
+// nextWeekday finds the next day matching ByWeekday that falls on a week
+// (starting on Monday) which is a multiple of Interval weeks away from the
+// week of the given day.
+func (rec *Recurrence) nextWeekday(from time.Time) time.Time {
+	if len(rec.ByWeekday) == 0 {
+		return from.AddDate(0, 0, 7*rec.Interval)
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, day := range rec.ByWeekday {
+		days[weekdays[day]] = true
+	}
+
+	weekStart := from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+	for i := 1; i <= 7*rec.Interval+7; i++ {
+		next := from.AddDate(0, 0, i)
+		week := int(next.Sub(weekStart).Hours()/24) / 7
+		if week%rec.Interval == 0 && days[next.Weekday()] {
+			return next
+		}
+	}
+
+	return from.AddDate(0, 0, 7*rec.Interval)
+}
+
+// addMonths adds the given months to a date, clamping the day to the end of
+// the resulting month instead of overflowing into the next one.
+func addMonths(from time.Time, months int) time.Time {
+	year, month, day := from.Date()
+	last := time.Date(year, month+time.Month(months)+1, 0, 0, 0, 0, 0, from.Location()).Day()
+	if day > last {
+		day = last
+	}
+
+	return time.Date(year, month+time.Month(months), day, from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+}
@@ -12,12 +12,18 @@ type AppResponse struct {
 	Detail  string `json:"detail,omitempty"`
 
 	Data map[string]interface{} `json:"data,omitempty"`
+
+	Headers http.Header `json:"-"`
 }
 
 func (res *AppResponse) GetCode() int {
 	return res.Code
 }
 
+func (res *AppResponse) GetHeaders() http.Header {
+	return res.Headers
+}
+
 func (res *AppResponse) ToJson() string {
 	json, _ := json.Marshal(res)
 	return string(json)
@@ -33,6 +39,16 @@ func (res *AppResponse) AddObject(key string, value interface{}) *AppResponse {
 	return res
 }
 
+func (res *AppResponse) SetHeader(key, value string) *AppResponse {
+	if res.Headers == nil {
+		res.Headers = make(http.Header)
+	}
+
+	res.Headers.Set(key, value)
+
+	return res
+}
+
 func NewCreatedResponse(message string) *AppResponse {
 	return &AppResponse{Code: http.StatusCreated, Message: message}
 }
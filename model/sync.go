@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TodoChange records the last change made to a todo, numbered by a sequence
+// that only ever grows. Todos no longer found, or deleted, are tombstones.
+type TodoChange struct {
+	Sequence  int64 `gorm:"primaryKey"`
+	TodoID    int
+	UserID    int
+	ChangedAt *time.Time
+}
+
+// TodoSync holds the todos changed after a cursor, oldest change first, and
+// the cursor to give on the next sync.
+type TodoSync struct {
+	Todos   []Todo `json:"todos"`
+	Deleted []int  `json:"deleted"`
+	Cursor  int64  `json:"cursor"`
+	HasMore bool   `json:"hasMore"`
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/jvitoroc/todo-go/util"
+)
+
+var colorRegex = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+
+type Tag struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"tagId"`
+	UserID    int       `gorm:"uniqueIndex:idx_tags_user_name" json:"userId"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID" json:"-"`
+	Name      string    `gorm:"uniqueIndex:idx_tags_user_name" json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type UpdateTag struct {
+	ID    int     `json:"tagId"`
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+func TagFromJson(data io.Reader) (*Tag, *AppError) {
+	tag := &Tag{}
+	if err := util.FromJson(data, tag); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return tag, nil
+}
+
+func UpdateTagFromJson(data io.Reader) (*UpdateTag, *AppError) {
+	update := &UpdateTag{}
+	if err := util.FromJson(data, update); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return update, nil
+}
+
+func (tag *Tag) Validate() *AppError {
+	errors := map[string]string{}
+
+	if tag.Name == "" {
+		errors["name"] = MSG_TAG_NAME_MISSING
+	}
+
+	if tag.Color != "" && !colorRegex.MatchString(tag.Color) {
+		errors["color"] = MSG_TAG_COLOR_INVALID
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
+
+func (tag *UpdateTag) Validate() *AppError {
+	errors := map[string]string{}
+
+	if tag.Name != nil && *tag.Name == "" {
+		errors["name"] = MSG_TAG_NAME_MISSING
+	}
+
+	if tag.Color != nil && *tag.Color != "" && !colorRegex.MatchString(*tag.Color) {
+		errors["color"] = MSG_TAG_COLOR_INVALID
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/jvitoroc/todo-go/util"
+)
+
+// Template is a snapshot of a todo subtree a user can instantiate as many
+// times as needed. Due and remind dates are kept as offsets, so that every
+// instance gets dates relative to when it is started.
+type Template struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"templateId"`
+	UserID    int            `gorm:"index" json:"userId"`
+	User      User           `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID" json:"-"`
+	Name      string         `json:"name"`
+	Items     []TemplateItem `gorm:"constraint:OnDelete:CASCADE" json:"items,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// TemplateItem is a todo of a template. Offsets are in seconds from the due
+// date of the template root, or from when the template was created if the
+// root had no due date.
+type TemplateItem struct {
+	ID           int           `gorm:"primaryKey;autoIncrement" json:"templateItemId"`
+	TemplateID   int           `gorm:"index" json:"templateId"`
+	ParentItemID *int          `gorm:"index" json:"parentItemId"`
+	ParentItem   *TemplateItem `gorm:"constraint:OnDelete:CASCADE;foreignkey:ParentItemID;references:ID" json:"-"`
+	Description  string        `json:"description"`
+	Notes        string        `json:"notes"`
+	AutoComplete bool          `json:"autoComplete"`
+	Priority     int           `json:"priority"`
+	Position     string        `json:"position"`
+	DueOffset    *int64        `json:"dueOffset"`
+	RemindOffset *int64        `json:"remindOffset"`
+	Recurrence   *Recurrence   `gorm:"type:text" json:"recurrence"`
+	Tags         []Tag         `gorm:"many2many:template_item_tags;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+}
+
+type CreateTemplate struct {
+	TodoID int    `json:"todoId"`
+	Name   string `json:"name"`
+}
+
+// InstantiateTemplate creates the todos of a template under the given parent,
+// or at the root when it is null or missing. Due and remind dates are offset
+// from StartAt, which defaults to now. Placeholders like {{name}} in
+// descriptions and notes are replaced by Values, with {{date}} defaulting to
+// the StartAt date in Timezone.
+type InstantiateTemplate struct {
+	ID           int               `json:"templateId"`
+	ParentTodoID OptionalInt       `json:"parentTodoId"`
+	StartAt      *time.Time        `json:"startAt"`
+	Timezone     string            `json:"timezone"`
+	Values       map[string]string `json:"values"`
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+func CreateTemplateFromJson(data io.Reader) (*CreateTemplate, *AppError) {
+	create := &CreateTemplate{}
+	if err := util.FromJson(data, create); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return create, nil
+}
+
+func InstantiateTemplateFromJson(data io.Reader) (*InstantiateTemplate, *AppError) {
+	instantiate := &InstantiateTemplate{}
+	if err := util.FromJson(data, instantiate); err != nil && err != io.EOF {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return instantiate, nil
+}
+
+func (create *CreateTemplate) Validate() *AppError {
+	errors := map[string]string{}
+
+	if create.TodoID <= 0 {
+		errors["todoId"] = MSG_TEMPLATE_TODO_MISSING
+	}
+
+	if create.Name == "" {
+		errors["name"] = MSG_TEMPLATE_NAME_MISSING
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
+
+func (instantiate *InstantiateTemplate) Validate() *AppError {
+	if _, err := time.LoadLocation(instantiate.Timezone); err != nil {
+		return NewFormError(nil).AddError("timezone", fmt.Sprintf(MSG_TIMEZONE_INVALID, instantiate.Timezone))
+	}
+
+	return nil
+}
+
+// Substitute replaces the placeholders in text with the instantiation values.
+// Placeholders without a value are left as they are.
+func (instantiate *InstantiateTemplate) Substitute(text string, startAt time.Time) string {
+	return templatePlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		key := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		if value, ok := instantiate.Values[key]; ok {
+			return value
+		}
+
+		if key == "date" {
+			loc, _ := time.LoadLocation(instantiate.Timezone)
+			return startAt.In(loc).Format("2006-01-02")
+		}
+
+		return placeholder
+	})
+}
+
+// NewTemplateOffset returns the offset in seconds of the given date from the
+// start of the template, or nil when there is no date.
+func NewTemplateOffset(date *time.Time, start time.Time) *int64 {
+	if date == nil {
+		return nil
+	}
+
+	offset := int64(date.Sub(start) / time.Second)
+	return &offset
+}
+
+// TemplateOffsetDate is the inverse of NewTemplateOffset.
+func TemplateOffsetDate(offset *int64, start time.Time) *time.Time {
+	if offset == nil {
+		return nil
+	}
+
+	date := start.Add(time.Duration(*offset) * time.Second)
+	return &date
+}
@@ -1,32 +1,163 @@
 package model
 
 import (
+	"fmt"
 	"io"
 	"time"
 
 	"github.com/jvitoroc/todo-go/util"
+	"gorm.io/gorm"
 )
 
 type Todo struct {
-	ID           int       `gorm:"primaryKey;autoIncrement" json:"todoId"`
-	ParentTodoID *int      `json:"parentTodoId"`
-	ParentTodo   *Todo     `gorm:"constraint:OnDelete:CASCADE;foreignkey:ParentTodoID;references:ID" json:"-"`
-	UserID       int       `json:"userId"`
-	User         User      `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID" json:"-"`
-	Description  string    `json:"description"`
-	Completed    bool      `json:"completed"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID                int            `gorm:"primaryKey;autoIncrement" json:"todoId"`
+	ParentTodoID      *int           `gorm:"index:idx_todos_parent_position,priority:1" json:"parentTodoId"`
+	ParentTodo        *Todo          `gorm:"constraint:OnDelete:CASCADE;foreignkey:ParentTodoID;references:ID" json:"-"`
+	UserID            int            `gorm:"index:idx_todos_user_due,priority:1" json:"userId"`
+	User              User           `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID" json:"-"`
+	Description       string         `json:"description"`
+	Notes             string         `json:"notes"`
+	Completed         bool           `json:"completed"`
+	AutoComplete      bool           `json:"autoComplete"`
+	Priority          int            `json:"priority"`
+	Position          string         `gorm:"index:idx_todos_parent_position,priority:2" json:"position"`
+	DueAt             *time.Time     `gorm:"index:idx_todos_user_due,priority:2" json:"dueAt"`
+	RemindAt          *time.Time     `gorm:"index" json:"remindAt"`
+	RemindedAt        *time.Time     `json:"-"`
+	ReminderClaimedAt *time.Time     `json:"-"`
+	ReminderAttempts  int            `gorm:"default:0" json:"-"`
+	ReminderRetryAt   *time.Time     `json:"-"`
+	Recurrence        *Recurrence    `gorm:"type:text" json:"recurrence"`
+	Occurrence        int            `gorm:"default:1" json:"occurrence"`
+	NextOccurrenceID  *int           `json:"-"`
+	Version           int            `gorm:"default:1" json:"version"`
+	Tags              []Tag          `gorm:"many2many:todo_tags;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+	Progress          *TodoProgress  `gorm:"-" json:"progress,omitempty"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deletedAt"`
+}
+
+type TodoSearchResult struct {
+	Todo Todo   `json:"todo"`
+	Path []Todo `json:"path"`
+}
+
+type TodoFilter struct {
+	Due        string
+	Location   *time.Location
+	Tags       []string
+	Expression *FilterNode
+}
+
+// todoSortColumns whitelists the columns todos can be sorted by, along with
+// whether they are sorted in descending order by default.
+var todoSortColumns = map[string]struct {
+	column     string
+	descending bool
+}{
+	"created":     {"created_at", true},
+	"updated":     {"updated_at", true},
+	"due":         {"due_at", false},
+	"priority":    {"priority", true},
+	"position":    {"position", false},
+	"description": {"description", false},
+}
+
+type TodoSort struct {
+	Column     string
+	Descending bool
+}
+
+var DefaultTodoSort = &TodoSort{Column: "created_at", Descending: true}
+
+// TodoProgress is computed from the children and further descendants of a
+// todo, where Percent is the share of completed descendants.
+type TodoProgress struct {
+	ChildCount               int `json:"childCount"`
+	CompletedChildCount      int `json:"completedChildCount"`
+	DescendantCount          int `json:"descendantCount"`
+	CompletedDescendantCount int `json:"completedDescendantCount"`
+	Percent                  int `json:"percent"`
+}
+
+func NewTodoProgress(childCount, completedChildCount, descendantCount, completedDescendantCount int) *TodoProgress {
+	progress := &TodoProgress{
+		ChildCount:               childCount,
+		CompletedChildCount:      completedChildCount,
+		DescendantCount:          descendantCount,
+		CompletedDescendantCount: completedDescendantCount,
+	}
+
+	if descendantCount > 0 {
+		progress.Percent = completedDescendantCount * 100 / descendantCount
+	}
+
+	return progress
+}
+
+// AllChildrenCompleted tells whether there are children and all of them are
+// completed, which is when an auto-completed todo is completed itself.
+func (progress *TodoProgress) AllChildrenCompleted() bool {
+	return progress.ChildCount > 0 && progress.CompletedChildCount == progress.ChildCount
+}
+
+type TodoNode struct {
+	Todo
+	Children []*TodoNode `json:"children"`
 }
 
 type UpdateTodo struct {
-	ID          int     `json:"todoId"`
-	Description *string `json:"description"`
-	Completed   *bool   `json:"completed"`
+	ID           int                `json:"todoId"`
+	Description  *string            `json:"description"`
+	Notes        *string            `json:"notes"`
+	Completed    *bool              `json:"completed"`
+	AutoComplete *bool              `json:"autoComplete"`
+	Priority     *int               `json:"priority"`
+	DueAt        OptionalTime       `json:"dueAt"`
+	RemindAt     OptionalTime       `json:"remindAt"`
+	Recurrence   OptionalRecurrence `json:"recurrence"`
+	Version      *int               `json:"version"`
+}
+
+type MoveTodo struct {
+	ID           int  `json:"todoId"`
+	ParentTodoID *int `json:"parentTodoId"`
+	Version      *int `json:"version"`
+}
+
+// DuplicateTodo copies a todo along with its subtree, under the same parent
+// unless another one is given (null being the root).
+type DuplicateTodo struct {
+	ID             int         `json:"todoId"`
+	ParentTodoID   OptionalInt `json:"parentTodoId"`
+	ResetCompleted bool        `json:"resetCompleted"`
 }
 
+type ReorderTodo struct {
+	ID      int  `json:"todoId"`
+	Before  *int `json:"before"`
+	After   *int `json:"after"`
+	Version *int `json:"version"`
+}
+
+// UpdateManyTodos holds the changes applied to every todo in IDs, only made
+// if each of them is still at the version given for it in Versions. Tags
+// replace those of each todo, and a null parent moves them to the root.
+type UpdateManyTodos struct {
+	IDs          []int       `json:"ids"`
+	Completed    *bool       `json:"completed"`
+	Priority     *int        `json:"priority"`
+	TagIDs       *[]int      `json:"tagIds"`
+	ParentTodoID OptionalInt `json:"parentTodoId"`
+	Versions     map[int]int `json:"versions"`
+}
+
+// DeleteManyTodos holds the todos to delete. Like UpdateManyTodos, it must
+// give in Versions the version each of them is expected to be at.
 type DeleteManyTodos struct {
-	IDs []int `json:"ids"`
+	IDs      []int       `json:"ids"`
+	Versions map[int]int `json:"versions"`
 }
 
 func TodoFromJson(data io.Reader) (*Todo, *AppError) {
@@ -35,6 +166,41 @@ func TodoFromJson(data io.Reader) (*Todo, *AppError) {
 		return nil, NewGenericBadRequestError(err)
 	}
 
+	// only the todo it recurs from decides the occurrence, so that clients
+	// cannot skip past the count of the recurrence
+	todo.Occurrence = 1
+	todo.Version = 1
+
+	// so does the rest kept by the server, otherwise a client could even
+	// create a todo straight into the trash
+	todo.ID = 0
+	todo.Position = ""
+	todo.RemindedAt = nil
+	todo.ReminderClaimedAt = nil
+	todo.ReminderAttempts = 0
+	todo.ReminderRetryAt = nil
+	todo.NextOccurrenceID = nil
+	todo.Tags = nil
+	todo.Progress = nil
+	todo.CreatedAt = time.Time{}
+	todo.UpdatedAt = time.Time{}
+	todo.DeletedAt = gorm.DeletedAt{}
+
+	// store every date in UTC so they can be compared in the database
+	if todo.DueAt != nil {
+		dueAt := todo.DueAt.UTC()
+		todo.DueAt = &dueAt
+	}
+
+	if todo.RemindAt != nil {
+		remindAt := todo.RemindAt.UTC()
+		todo.RemindAt = &remindAt
+	}
+
+	if todo.Recurrence != nil {
+		todo.Recurrence.normalize()
+	}
+
 	return todo, nil
 }
 
@@ -44,6 +210,47 @@ func UpdateTodoFromJson(data io.Reader) (*UpdateTodo, *AppError) {
 		return nil, NewGenericBadRequestError(err)
 	}
 
+	if update.Recurrence.Recurrence != nil {
+		update.Recurrence.Recurrence.normalize()
+	}
+
+	return update, nil
+}
+
+func MoveTodoFromJson(data io.Reader) (*MoveTodo, *AppError) {
+	move := &MoveTodo{}
+	if err := util.FromJson(data, move); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return move, nil
+}
+
+// DuplicateTodoFromJson accepts an empty body, since every field is optional.
+func DuplicateTodoFromJson(data io.Reader) (*DuplicateTodo, *AppError) {
+	duplicate := &DuplicateTodo{}
+	if err := util.FromJson(data, duplicate); err != nil && err != io.EOF {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return duplicate, nil
+}
+
+func ReorderTodoFromJson(data io.Reader) (*ReorderTodo, *AppError) {
+	reorder := &ReorderTodo{}
+	if err := util.FromJson(data, reorder); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return reorder, nil
+}
+
+func UpdateManyTodosFromJson(data io.Reader) (*UpdateManyTodos, *AppError) {
+	update := &UpdateManyTodos{}
+	if err := util.FromJson(data, update); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
 	return update, nil
 }
 
@@ -56,6 +263,109 @@ func DeleteManyTodosFromJson(data io.Reader) (*DeleteManyTodos, *AppError) {
 	return delete, nil
 }
 
+func (filter *TodoFilter) IsEmpty() bool {
+	return filter.Due == "" && len(filter.Tags) == 0 && filter.Expression == nil
+}
+
+// NewTodoSort builds a sort from the given field and order, returning nil
+// when no field is given so callers can fall back to their default sort.
+func NewTodoSort(field, order string) (*TodoSort, *AppError) {
+	if field == "" {
+		if order != "" {
+			return nil, NewFormError(nil).AddError("order", MSG_TODO_ORDER_WITHOUT_SORT)
+		}
+		return nil, nil
+	}
+
+	errors := map[string]string{}
+
+	sort, ok := todoSortColumns[field]
+	if !ok {
+		errors["sort"] = MSG_TODO_SORT_INVALID
+	}
+
+	descending := sort.descending
+	switch order {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		errors["order"] = MSG_TODO_ORDER_INVALID
+	}
+
+	if len(errors) == 0 {
+		return &TodoSort{Column: sort.column, Descending: descending}, nil
+	} else {
+		return nil, NewFormError(errors)
+	}
+}
+
+// NewTodoTree nests a flat list of todos under the todo with the given id,
+// or under the root when parentId is nil. Sibling order is preserved.
+func NewTodoTree(parentId *int, todos []Todo) []*TodoNode {
+	nodes := make(map[int]*TodoNode, len(todos))
+	for i := range todos {
+		nodes[todos[i].ID] = &TodoNode{Todo: todos[i], Children: make([]*TodoNode, 0)}
+	}
+
+	roots := make([]*TodoNode, 0)
+	for i := range todos {
+		node := nodes[todos[i].ID]
+		if todos[i].ParentTodoID == nil || (parentId != nil && *todos[i].ParentTodoID == *parentId) {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*todos[i].ParentTodoID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots
+}
+
+// ETag identifies the current version of the todo, so that clients can make
+// their changes conditional on it through If-Match.
+func (todo *Todo) ETag() string {
+	return fmt.Sprintf(`"%d"`, todo.Version)
+}
+
+// PayloadETag identifies a response built around the todo, such as one also
+// holding its children. It changes along with the whole payload, while still
+// starting with the version of the todo so that it works with If-Match.
+func (todo *Todo) PayloadETag(payload string) string {
+	return fmt.Sprintf(`"%d.%s"`, todo.Version, util.Digest(payload))
+}
+
+// CheckVersion fails with the current todo attached when it is no longer at
+// the version the client expected, if any.
+func (todo *Todo) CheckVersion(version *int) *AppError {
+	if version == nil || *version == todo.Version {
+		return nil
+	}
+
+	return NewPreconditionFailedError(fmt.Sprintf(MSG_TODO_VERSION_MISMATCH, *version)).AddObject("todo", todo)
+}
+
+// CheckTodoVersionsGiven makes sure a version is given for each of the todos,
+// as changing them requires it.
+func CheckTodoVersionsGiven(todoIds []int, versions map[int]int) *AppError {
+	for _, id := range todoIds {
+		if _, ok := versions[id]; !ok {
+			return NewPreconditionRequiredError(fmt.Sprintf(MSG_TODO_VERSIONS_MISSING, id))
+		}
+	}
+
+	return nil
+}
+
+func (todo *Todo) IsSiblingOf(other *Todo) bool {
+	if todo.ParentTodoID == nil || other.ParentTodoID == nil {
+		return todo.ParentTodoID == other.ParentTodoID && todo.UserID == other.UserID
+	}
+
+	return *todo.ParentTodoID == *other.ParentTodoID && todo.UserID == other.UserID
+}
+
 func (todo *Todo) Validate() *AppError {
 	errors := map[string]string{}
 
@@ -63,6 +373,14 @@ func (todo *Todo) Validate() *AppError {
 		errors["description"] = MSG_TODO_DESCRIPTION_MISSING
 	}
 
+	if todo.Priority < TODO_PRIORITY_MINIMUM || todo.Priority > TODO_PRIORITY_MAXIMUM {
+		errors["priority"] = fmt.Sprintf(MSG_TODO_PRIORITY_INVALID, TODO_PRIORITY_MINIMUM, TODO_PRIORITY_MAXIMUM)
+	}
+
+	if todo.Recurrence != nil {
+		todo.Recurrence.Validate(errors)
+	}
+
 	if len(errors) == 0 {
 		return nil
 	} else {
@@ -77,6 +395,46 @@ func (todo *UpdateTodo) Validate() *AppError {
 		errors["description"] = MSG_TODO_DESCRIPTION_MISSING
 	}
 
+	if todo.Priority != nil && (*todo.Priority < TODO_PRIORITY_MINIMUM || *todo.Priority > TODO_PRIORITY_MAXIMUM) {
+		errors["priority"] = fmt.Sprintf(MSG_TODO_PRIORITY_INVALID, TODO_PRIORITY_MINIMUM, TODO_PRIORITY_MAXIMUM)
+	}
+
+	if todo.Recurrence.Recurrence != nil {
+		todo.Recurrence.Recurrence.Validate(errors)
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
+
+func (reorder *ReorderTodo) Validate() *AppError {
+	errors := map[string]string{}
+
+	if reorder.Before == nil && reorder.After == nil {
+		errors["before"] = MSG_TODO_REORDER_MISSING
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
+
+func (todo *UpdateManyTodos) Validate() *AppError {
+	errors := map[string]string{}
+
+	if len(todo.IDs) == 0 {
+		errors["ids"] = MSG_TODO_IDS_NOT_PROVIDED
+	}
+
+	if todo.Priority != nil && (*todo.Priority < TODO_PRIORITY_MINIMUM || *todo.Priority > TODO_PRIORITY_MAXIMUM) {
+		errors["priority"] = fmt.Sprintf(MSG_TODO_PRIORITY_INVALID, TODO_PRIORITY_MINIMUM, TODO_PRIORITY_MAXIMUM)
+	}
+
 	if len(errors) == 0 {
 		return nil
 	} else {
@@ -87,7 +445,7 @@ func (todo *UpdateTodo) Validate() *AppError {
 func (todo *DeleteManyTodos) Validate() *AppError {
 	errors := map[string]string{}
 
-	if todo.IDs != nil {
+	if len(todo.IDs) == 0 {
 		errors["ids"] = MSG_TODO_IDS_NOT_PROVIDED
 	}
 
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UndoOperation records how to revert a destructive operation, redeemable
+// once through its token until it expires.
+type UndoOperation struct {
+	Token     string `gorm:"primaryKey"`
+	UserID    int    `gorm:"index"`
+	User      User   `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID"`
+	Kind      string
+	Payload   string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// UndoDelete lists every todo soft deleted by the operation, descendants
+// included, so that exactly those are restored.
+type UndoDelete struct {
+	IDs []int `json:"ids"`
+}
+
+// UndoMove holds where the todo was before being moved, and the parent it
+// was moved to.
+type UndoMove struct {
+	ID           int    `json:"id"`
+	ParentTodoID *int   `json:"parentTodoId"`
+	Position     string `json:"position"`
+	MovedTo      *int   `json:"movedTo"`
+}
+
+func NewUndoOperation(token string, userId int, kind string, payload interface{}, now time.Time) (*UndoOperation, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UndoOperation{
+		Token:     token,
+		UserID:    userId,
+		Kind:      kind,
+		Payload:   string(data),
+		ExpiresAt: now.Add(UNDO_EXPIRATION * time.Second),
+	}, nil
+}
+
+func (undo *UndoOperation) DecodePayload(payload interface{}) error {
+	return json.Unmarshal([]byte(undo.Payload), payload)
+}
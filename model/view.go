@@ -0,0 +1,109 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jvitoroc/todo-go/util"
+)
+
+// View is a named filter and sort saved by a user, listing the todos
+// matching it whenever it is opened.
+type View struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"viewId"`
+	UserID    int       `gorm:"index" json:"userId"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE;foreignkey:UserID;references:ID" json:"-"`
+	Name      string    `json:"name"`
+	Filter    string    `json:"filter"`
+	Due       string    `json:"due"`
+	Sort      string    `json:"sort"`
+	Order     string    `json:"order"`
+	Timezone  string    `json:"timezone"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type UpdateView struct {
+	ID       int     `json:"viewId"`
+	Name     *string `json:"name"`
+	Filter   *string `json:"filter"`
+	Due      *string `json:"due"`
+	Sort     *string `json:"sort"`
+	Order    *string `json:"order"`
+	Timezone *string `json:"timezone"`
+}
+
+func ViewFromJson(data io.Reader) (*View, *AppError) {
+	view := &View{}
+	if err := util.FromJson(data, view); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return view, nil
+}
+
+func UpdateViewFromJson(data io.Reader) (*UpdateView, *AppError) {
+	update := &UpdateView{}
+	if err := util.FromJson(data, update); err != nil {
+		return nil, NewGenericBadRequestError(err)
+	}
+
+	return update, nil
+}
+
+// TodoFilter builds the filter the view was saved with. Relative due filters
+// and dates in the filter expression follow the view timezone.
+func (view *View) TodoFilter() (*TodoFilter, *AppError) {
+	loc, err := time.LoadLocation(view.Timezone)
+	if err != nil {
+		return nil, NewFormError(nil).AddError("timezone", fmt.Sprintf(MSG_TIMEZONE_INVALID, view.Timezone))
+	}
+
+	filter := &TodoFilter{Due: view.Due, Location: loc}
+	if view.Filter != "" {
+		expression, err := ParseTodoFilter(view.Filter, loc)
+		if err != nil {
+			return nil, NewFormError(nil).AddError("filter", err.Detail)
+		}
+		filter.Expression = expression
+	}
+
+	return filter, nil
+}
+
+func (view *View) TodoSort() (*TodoSort, *AppError) {
+	return NewTodoSort(view.Sort, view.Order)
+}
+
+func (view *View) Validate() *AppError {
+	errors := map[string]string{}
+
+	if view.Name == "" {
+		errors["name"] = MSG_VIEW_NAME_MISSING
+	}
+
+	switch view.Due {
+	case "", TODO_DUE_OVERDUE, TODO_DUE_TODAY, TODO_DUE_WEEK:
+	default:
+		errors["due"] = MSG_TODO_DUE_INVALID
+	}
+
+	if _, err := view.TodoFilter(); err != nil {
+		for key, message := range err.Errors {
+			errors[key] = message
+		}
+	}
+
+	if _, err := view.TodoSort(); err != nil {
+		for key, message := range err.Errors {
+			errors[key] = message
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	} else {
+		return NewFormError(errors)
+	}
+}
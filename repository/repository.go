@@ -11,6 +11,10 @@ import (
 
 type Repository struct {
 	DB *gorm.DB
+
+	FullTextSearch bool // whether the SQLite driver was built with FTS5
+
+	inTran bool
 }
 
 func NewRepository(cfg *config.Config) *Repository {
@@ -19,13 +23,42 @@ func NewRepository(cfg *config.Config) *Repository {
 	db.AutoMigrate(model.User{})
 	db.AutoMigrate(model.VerificationRequest{})
 	db.AutoMigrate(model.Todo{})
+	db.AutoMigrate(model.Tag{})
+	db.AutoMigrate(model.View{})
+	db.AutoMigrate(model.UndoOperation{})
+	db.AutoMigrate(model.Template{})
+	db.AutoMigrate(model.TemplateItem{})
+
+	if err := migrateTodoVersions(db); err != nil {
+		log.Fatalf("Could not create the todo version triggers: %s", err.Error())
+	}
+
+	if err := migrateTodoChanges(db); err != nil {
+		log.Fatalf("Could not create the todo change log: %s", err.Error())
+	}
+
+	fullTextSearch, err := migrateTodoSearch(db)
+	if err != nil {
+		log.Fatalf("Could not create the todo search index: %s", err.Error())
+	}
+
+	if !fullTextSearch {
+		log.Printf("Full-text search is unavailable, build with make or -tags sqlite_fts5 to enable it")
+	}
 
 	return &Repository{
-		DB: db,
+		DB:             db,
+		FullTextSearch: fullTextSearch,
 	}
 }
 
+// BeginTran runs fn within a transaction, rolled back if fn returns an error.
+// When the repository is already in a transaction, fn simply joins it.
 func (u *Repository) BeginTran(fn func(*Repository) *model.AppError) *model.AppError {
+	if u.inTran {
+		return fn(u)
+	}
+
 	tx := u.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -37,7 +70,7 @@ func (u *Repository) BeginTran(fn func(*Repository) *model.AppError) *model.AppE
 		return model.NewGenericInternalError(err)
 	}
 
-	tran := &Repository{DB: tx}
+	tran := &Repository{DB: tx, FullTextSearch: u.FullTextSearch, inTran: true}
 	if err := fn(tran); err != nil {
 		tran.DB.Rollback()
 		return err
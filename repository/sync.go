@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+	"gorm.io/gorm"
+)
+
+// migrateTodoChanges creates the change log used by sync, kept by triggers so
+// that every write to todos is covered, including cascading deletes and tag
+// changes. Each todo keeps only its latest change, and AUTOINCREMENT makes
+// sure a sequence is never handed out twice. Changes are timestamped down to
+// the millisecond. Triggers are recreated on every start so that changes to
+// them reach existing databases.
+func migrateTodoChanges(db *gorm.DB) error {
+	var count int64
+	if err := db.Raw("SELECT count(*) FROM sqlite_master WHERE name = 'todo_changes'").Scan(&count).Error; err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS todo_changes (
+			sequence integer PRIMARY KEY AUTOINCREMENT,
+			todo_id integer NOT NULL UNIQUE,
+			user_id integer NOT NULL,
+			changed_at datetime
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_todo_changes_user_sequence ON todo_changes(user_id, sequence)`,
+	}
+
+	// logs created before changes were timestamped
+	if count > 0 && !db.Migrator().HasColumn(&model.TodoChange{}, "changed_at") {
+		statements = append(statements, `ALTER TABLE todo_changes ADD COLUMN changed_at datetime`)
+	}
+
+	triggers := map[string]string{
+		"todo_changes_insert": `AFTER INSERT ON todos BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at) VALUES (new.id, new.user_id, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END`,
+		// reminded_at is left out since clients never see it
+		"todo_changes_update": `AFTER UPDATE OF
+			parent_todo_id, description, notes, completed, auto_complete, priority, position,
+			due_at, remind_at, recurrence, occurrence, updated_at, deleted_at ON todos BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at) VALUES (new.id, new.user_id, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END`,
+		"todo_changes_delete": `AFTER DELETE ON todos BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at) VALUES (old.id, old.user_id, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END`,
+		"todo_changes_tag_insert": `AFTER INSERT ON todo_tags BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at)
+			SELECT id, user_id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM todos WHERE id = new.todo_id;
+		END`,
+		"todo_changes_tag_delete": `AFTER DELETE ON todo_tags BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at)
+			SELECT id, user_id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM todos WHERE id = old.todo_id;
+		END`,
+		"todo_changes_tag_update": `AFTER UPDATE OF name, color ON tags BEGIN
+			INSERT OR REPLACE INTO todo_changes(todo_id, user_id, changed_at)
+			SELECT t.id, t.user_id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM todos t JOIN todo_tags tt ON tt.todo_id = t.id WHERE tt.tag_id = new.id;
+		END`,
+	}
+
+	for name, definition := range triggers {
+		statements = append(statements, "DROP TRIGGER IF EXISTS "+name, "CREATE TRIGGER "+name+" "+definition)
+	}
+
+	// log the todos created before the change log existed
+	if count == 0 {
+		statements = append(statements, `INSERT OR IGNORE INTO todo_changes(todo_id, user_id, changed_at) SELECT id, user_id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM todos ORDER BY id`)
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTodoChanges returns up to limit changes made to the todos of the user
+// after the given sequence, oldest first.
+func (t *Repository) GetTodoChanges(userId int, since int64, limit int) ([]model.TodoChange, *model.AppError) {
+	changes := []model.TodoChange{}
+	if err := t.DB.Where("user_id = ? and sequence > ?", userId, since).Order("sequence ASC").Limit(limit).Find(&changes).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return changes, nil
+}
+
+// GetTodosLastChanged returns when any todo of the user last changed, or nil
+// when there is no change on record.
+func (t *Repository) GetTodosLastChanged(userId int) (*time.Time, *model.AppError) {
+	changes := []model.TodoChange{}
+	if err := t.DB.Where("user_id = ?", userId).Order("sequence DESC").Limit(1).Find(&changes).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(changes) == 0 || changes[0].ChangedAt == nil {
+		return nil, nil
+	}
+
+	return changes[0].ChangedAt, nil
+}
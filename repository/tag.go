@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jvitoroc/todo-go/model"
+	"gorm.io/gorm"
+)
+
+func (t *Repository) CreateTag(tag *model.Tag) (*model.Tag, *model.AppError) {
+	if err := t.DB.Create(tag).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return tag, nil
+}
+
+func (t *Repository) GetTag(tagId int, userId int) (*model.Tag, *model.AppError) {
+	tag := model.Tag{}
+	if err := t.DB.Where("user_id = ?", userId).First(&tag, tagId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_TAG_NOT_FOUND, tagId))
+		} else {
+			return nil, model.NewGenericInternalError(err)
+		}
+	}
+
+	return &tag, nil
+}
+
+func (t *Repository) GetTags(userId int) ([]model.Tag, *model.AppError) {
+	tags := []model.Tag{}
+	if err := t.DB.Where("user_id = ?", userId).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return tags, nil
+}
+
+func (t *Repository) UpdateTag(tag *model.Tag) *model.AppError {
+	if err := t.DB.Save(tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.NewNotFoundError(fmt.Sprintf(model.MSG_TAG_NOT_FOUND, tag.ID))
+		} else {
+			return model.NewGenericInternalError(err)
+		}
+	}
+
+	return nil
+}
+
+func (t *Repository) DeleteTag(tagId, userId int) *model.AppError {
+	var result *gorm.DB
+	if result = t.DB.Where("id = ? and user_id = ?", tagId, userId).Delete(&model.Tag{}); result.Error != nil {
+		return model.NewGenericInternalError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewNotFoundError(fmt.Sprintf(model.MSG_TAG_NOT_FOUND, tagId))
+	}
+
+	return nil
+}
+
+// GetTaggedTodoIDs returns the ids of the todos carrying the given tag, leaving
+// out those in the trash.
+func (t *Repository) GetTaggedTodoIDs(tagId int) ([]int, *model.AppError) {
+	ids := []int{}
+	if err := t.DB.Model(&model.Todo{}).Joins("JOIN todo_tags ON todo_tags.todo_id = todos.id").Where("todo_tags.tag_id = ?", tagId).Pluck("todos.id", &ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return ids, nil
+}
+
+func (t *Repository) CheckIfTagNameExists(name string, userId int) (bool, *model.AppError) {
+	var count int64
+	if err := t.DB.Model(&model.Tag{}).Where("name = ? and user_id = ?", name, userId).Count(&count).Error; err != nil {
+		return false, model.NewGenericInternalError(err)
+	}
+
+	return count > 0, nil
+}
+
+func (t *Repository) AddTodoTag(todo *model.Todo, tag *model.Tag) *model.AppError {
+	if err := t.DB.Model(todo).Association("Tags").Append(tag); err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
+
+func (t *Repository) RemoveTodoTag(todo *model.Todo, tag *model.Tag) *model.AppError {
+	if err := t.DB.Model(todo).Association("Tags").Delete(tag); err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
+
+func (t *Repository) SetTodoTags(todo *model.Todo, tags []model.Tag) *model.AppError {
+	if err := t.DB.Model(todo).Association("Tags").Replace(tags); err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jvitoroc/todo-go/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (t *Repository) CreateTemplate(template *model.Template) (*model.Template, *model.AppError) {
+	if err := t.DB.Omit(clause.Associations).Create(template).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return template, nil
+}
+
+func (t *Repository) CreateTemplateItem(item *model.TemplateItem) (*model.TemplateItem, *model.AppError) {
+	if err := t.DB.Omit(clause.Associations).Create(item).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(item.Tags) > 0 {
+		if err := t.DB.Model(item).Association("Tags").Replace(item.Tags); err != nil {
+			return nil, model.NewGenericInternalError(err)
+		}
+	}
+
+	return item, nil
+}
+
+// GetTemplate loads the template along with its items, parents always coming
+// before their children.
+func (t *Repository) GetTemplate(templateId int, userId int) (*model.Template, *model.AppError) {
+	template := model.Template{}
+	err := t.DB.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Items.Tags").
+		Where("user_id = ?", userId).
+		First(&template, templateId).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_TEMPLATE_NOT_FOUND, templateId))
+		} else {
+			return nil, model.NewGenericInternalError(err)
+		}
+	}
+
+	return &template, nil
+}
+
+func (t *Repository) GetTemplates(userId int) ([]model.Template, *model.AppError) {
+	templates := []model.Template{}
+	if err := t.DB.Where("user_id = ?", userId).Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return templates, nil
+}
+
+func (t *Repository) DeleteTemplate(templateId, userId int) *model.AppError {
+	var result *gorm.DB
+	if result = t.DB.Where("id = ? and user_id = ?", templateId, userId).Delete(&model.Template{}); result.Error != nil {
+		return model.NewGenericInternalError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewNotFoundError(fmt.Sprintf(model.MSG_TEMPLATE_NOT_FOUND, templateId))
+	}
+
+	return nil
+}
@@ -3,13 +3,18 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (t *Repository) CreateTodo(todo *model.Todo) (*model.Todo, *model.AppError) {
-	if err := t.DB.Create(todo).Error; err != nil {
+	if err := t.DB.Omit(clause.Associations).Create(todo).Error; err != nil {
 		return nil, model.NewGenericInternalError(err)
 	}
 
@@ -18,7 +23,7 @@ func (t *Repository) CreateTodo(todo *model.Todo) (*model.Todo, *model.AppError)
 
 func (t *Repository) GetTodo(todoId int, userId int) (*model.Todo, *model.AppError) {
 	todo := model.Todo{}
-	if err := t.DB.Where("user_id = ?", userId).First(&todo, todoId).Error; err != nil {
+	if err := t.DB.Preload("Tags").Where("user_id = ?", userId).First(&todo, todoId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_TODO_NOT_FOUND, todoId))
 		} else {
@@ -29,54 +34,787 @@ func (t *Repository) GetTodo(todoId int, userId int) (*model.Todo, *model.AppErr
 	return &todo, nil
 }
 
-func (t *Repository) GetTodoChildren(todoId int, userId int) ([]model.Todo, *model.AppError) {
+// GetTodos lists the todos of a user across the whole tree, narrowed down by
+// the given scopes.
+func (t *Repository) GetTodos(userId int, sort *model.TodoSort, scopes ...func(*gorm.DB) *gorm.DB) ([]model.Todo, *model.AppError) {
 	todos := []model.Todo{}
-	if err := t.DB.Where("parent_todo_id = ? and user_id = ?", todoId, userId).Order("created_at DESC").Find(&todos).Error; err != nil {
+	if err := t.DB.Preload("Tags").Scopes(scopes...).Where("todos.user_id = ?", userId).Order(orderTodosBy(sort)).Find(&todos).Error; err != nil {
 		return nil, model.NewGenericInternalError(err)
 	}
 
 	return todos, nil
 }
 
-func (t *Repository) GetRootTodoChildren(userId int) ([]model.Todo, *model.AppError) {
+// orderTodosBy builds the ORDER BY clause for the given sort, which is safe to
+// inline since its column always comes from the model whitelist. Todos with no
+// value for the column go last, and ids break ties so pages are stable.
+func orderTodosBy(sort *model.TodoSort) string {
+	if sort == nil {
+		sort = model.DefaultTodoSort
+	}
+
+	direction := "ASC"
+	if sort.Descending {
+		direction = "DESC"
+	}
+
+	return fmt.Sprintf("todos.%s is null, todos.%s %s, todos.id %s", sort.Column, sort.Column, direction, direction)
+}
+
+// TodosAfter keeps the todos coming after the given cursor, following the same
+// order as orderTodosBy. The cursor column has already been checked against
+// the sort whitelist.
+func TodosAfter(cursor *model.TodoCursor) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		column := "todos." + cursor.Column
+		comparison := ">"
+		if cursor.Descending {
+			comparison = "<"
+		}
+
+		value, _ := cursor.TypedValue()
+		if value == nil {
+			return db.Where(fmt.Sprintf("(%s is null and todos.id %s ?)", column, comparison), cursor.ID)
+		}
+
+		return db.Where(fmt.Sprintf("(%s is null or %s %s ? or (%s = ? and todos.id %s ?))", column, column, comparison, column, comparison), value, value, cursor.ID)
+	}
+}
+
+func TodosIn(todoIds []int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("todos.id in ?", todoIds)
+	}
+}
+
+func TodosLimit(limit int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Limit(limit)
+	}
+}
+
+func TodosOverdue(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("todos.due_at < ? and todos.completed = ?", now, false)
+	}
+}
+
+func TodosDueBetween(from, to time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("todos.due_at >= ? and todos.due_at < ?", from, to)
+	}
+}
+
+// TodosWithTags keeps the todos tagged with every one of the given tag names.
+func TodosWithTags(userId int, names []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`todos.id in (
+			SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id
+			WHERE tags.user_id = ? AND tags.name in ?
+			GROUP BY todo_tags.todo_id HAVING count(DISTINCT tags.id) = ?
+		)`, userId, names, len(names))
+	}
+}
+
+// TodosMatching keeps the todos matching a parsed filter. Columns and
+// comparators come from the parser whitelist, values are always bound.
+func TodosMatching(userId int, filter *model.FilterNode) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		condition, vars := filterCondition(userId, filter)
+		return db.Where(condition, vars...)
+	}
+}
+
+var filterOperators = map[string]string{":": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func filterCondition(userId int, node *model.FilterNode) (string, []interface{}) {
+	switch node.Op {
+	case model.FILTER_AND, model.FILTER_OR:
+		conditions := make([]string, len(node.Children))
+		vars := []interface{}{}
+		for i, child := range node.Children {
+			condition, childVars := filterCondition(userId, child)
+			conditions[i] = condition
+			vars = append(vars, childVars...)
+		}
+		return "(" + strings.Join(conditions, " "+node.Op+" ") + ")", vars
+	case model.FILTER_NOT:
+		condition, vars := filterCondition(userId, node.Children[0])
+		return "not (" + condition + ")", vars
+	}
+
+	column := "todos." + node.Column
+	negated := node.Comparator == "!="
+
+	switch node.Kind {
+	case model.FILTER_TAG:
+		operator := "in"
+		if negated {
+			operator = "not in"
+		}
+		return fmt.Sprintf(`todos.id %s (
+			SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id
+			WHERE tags.user_id = ? AND tags.name = ?
+		)`, operator), []interface{}{userId, node.Value}
+	case model.FILTER_TEXT:
+		operator := "like"
+		if negated {
+			operator = "not like"
+		}
+		return fmt.Sprintf(`%s %s ? escape '\'`, column, operator), []interface{}{"%" + likeEscaper.Replace(node.Value.(string)) + "%"}
+	case model.FILTER_DATE:
+		value, ok := node.Value.(time.Time)
+		if !ok {
+			if negated {
+				return column + " is not null", nil
+			}
+			return column + " is null", nil
+		}
+
+		// due dates are stored in UTC, while timestamps use the local time
+		if node.Column == "due_at" {
+			value = value.UTC()
+		} else {
+			value = value.Local()
+		}
+		return fmt.Sprintf("%s %s ?", column, filterOperators[node.Comparator]), []interface{}{value}
+	default:
+		return fmt.Sprintf("%s %s ?", column, filterOperators[node.Comparator]), []interface{}{node.Value}
+	}
+}
+
+// GetTodoAncestors returns the given todo followed by up to depth-1 of its
+// ancestors, ordered from the furthest ancestor down to the todo itself.
+func (t *Repository) GetTodoAncestors(todoId, userId, depth int) ([]model.Todo, *model.AppError) {
 	todos := []model.Todo{}
-	if err := t.DB.Where("parent_todo_id is null and user_id = ?", userId).Order("created_at DESC").Find(&todos).Error; err != nil {
+	if err := t.DB.Raw(`
+		WITH RECURSIVE ancestors(id, parent_todo_id, depth) AS (
+			SELECT id, parent_todo_id, 1 FROM todos WHERE id = @todo AND user_id = @user
+			UNION ALL
+			SELECT t.id, t.parent_todo_id, a.depth + 1 FROM todos t JOIN ancestors a ON t.id = a.parent_todo_id
+			WHERE t.user_id = @user AND a.depth < @depth
+		)
+		SELECT todos.* FROM todos JOIN ancestors ON todos.id = ancestors.id ORDER BY ancestors.depth DESC`,
+		map[string]interface{}{"todo": todoId, "user": userId, "depth": depth},
+	).Scan(&todos).Error; err != nil {
 		return nil, model.NewGenericInternalError(err)
 	}
 
 	return todos, nil
 }
 
+func (t *Repository) GetTodoChildren(todoId int, userId int, sort *model.TodoSort, scopes ...func(*gorm.DB) *gorm.DB) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+	if err := t.DB.Preload("Tags").Scopes(scopes...).Where("parent_todo_id = ? and user_id = ?", todoId, userId).Order(orderTodosBy(sort)).Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
+}
+
+func (t *Repository) GetRootTodoChildren(userId int, sort *model.TodoSort, scopes ...func(*gorm.DB) *gorm.DB) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+	if err := t.DB.Preload("Tags").Scopes(scopes...).Where("parent_todo_id is null and user_id = ?", userId).Order(orderTodosBy(sort)).Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
+}
+
+// GetTodoSubtree returns the descendants of the given todo (or of the root when
+// todoId is nil) down to the given depth, stopping after limit todos.
+func (t *Repository) GetTodoSubtree(todoId *int, userId, depth, limit int, sort *model.TodoSort) ([]model.Todo, *model.AppError) {
+	base := "SELECT id, 1 FROM todos WHERE parent_todo_id is null AND user_id = @user AND deleted_at is null"
+	if todoId != nil {
+		base = "SELECT id, 1 FROM todos WHERE parent_todo_id = @todo AND user_id = @user AND deleted_at is null"
+	}
+
+	todos := []model.Todo{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE subtree(id, depth) AS (
+			`+base+`
+			UNION ALL
+			SELECT t.id, s.depth + 1 FROM todos t JOIN subtree s ON t.parent_todo_id = s.id
+			WHERE t.user_id = @user AND t.deleted_at is null AND s.depth < @depth
+			LIMIT @limit
+		)
+		SELECT todos.* FROM todos JOIN subtree ON todos.id = subtree.id ORDER BY `+orderTodosBy(sort),
+		map[string]interface{}{"todo": todoId, "user": userId, "depth": depth, "limit": limit},
+	).Scan(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
+}
+
+// UpdateTodo saves the todo under its next version, failing if the todo is no
+// longer at the version it was loaded at. Checking the version in the same
+// statement as the write keeps concurrent updates from overwriting each
+// other. Other writes to todos get their version bumped by a trigger instead.
 func (t *Repository) UpdateTodo(todo *model.Todo) *model.AppError {
-	if err := t.DB.Save(todo).Error; err != nil {
+	version := todo.Version
+	todo.Version++
+
+	result := t.DB.Model(todo).Select("*").Omit(clause.Associations).Where("version = ?", version).Updates(todo)
+	if result.Error != nil {
+		todo.Version = version
+		return model.NewGenericInternalError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		todo.Version = version
+		return model.NewPreconditionFailedError(fmt.Sprintf(model.MSG_TODO_VERSION_CHANGED, version))
+	}
+
+	return nil
+}
+
+// DeleteTodo soft deletes the given todo along with its descendants,
+// returning the ids of every todo deleted.
+func (t *Repository) DeleteTodo(todoId, userId int) ([]int, *model.AppError) {
+	ids, err := t.trashTodos([]int{todoId}, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_TODO_NOT_FOUND, todoId))
+	}
+
+	return ids, nil
+}
+
+func (t *Repository) DeleteManyTodos(todoIds []int, userId int) ([]int, *model.AppError) {
+	return t.trashTodos(todoIds, userId)
+}
+
+// trashTodos soft deletes the given todos along with their descendants, all
+// sharing the same deletion time so that they are restored together.
+func (t *Repository) trashTodos(todoIds []int, userId int) ([]int, *model.AppError) {
+	ids := []int{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM todos WHERE id in @todos AND user_id = @user AND deleted_at is null
+			UNION
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_todo_id = s.id
+			WHERE t.deleted_at is null
+		)
+		SELECT id FROM subtree`,
+		map[string]interface{}{"todos": todoIds, "user": userId},
+	).Scan(&ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if err := t.DB.Model(&model.Todo{}).Where("id in ?", ids).UpdateColumn("deleted_at", t.DB.NowFunc()).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return ids, nil
+}
+
+// GetTrashedTodos lists the todos the user deleted, leaving out descendants
+// deleted along with their parent.
+func (t *Repository) GetTrashedTodos(userId int) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+	if err := t.DB.Unscoped().Preload("Tags").
+		Joins("LEFT JOIN todos parent ON parent.id = todos.parent_todo_id").
+		Where("todos.user_id = ? and todos.deleted_at is not null", userId).
+		Where("parent.deleted_at is null or parent.deleted_at <> todos.deleted_at").
+		Order("todos.deleted_at DESC, todos.id DESC").Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
+}
+
+func (t *Repository) GetTrashedTodo(todoId, userId int) (*model.Todo, *model.AppError) {
+	todo := model.Todo{}
+	if err := t.DB.Unscoped().Where("user_id = ? and deleted_at is not null", userId).First(&todo, todoId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return model.NewBadRequestError(fmt.Sprintf(model.MSG_TODO_NOT_FOUND, todo.ID))
+			return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_TODO_NOT_IN_TRASH, todoId))
 		} else {
-			return model.NewGenericInternalError(err)
+			return nil, model.NewGenericInternalError(err)
 		}
 	}
 
-	return nil
+	return &todo, nil
+}
+
+func (t *Repository) CheckIfTodoTrashed(todoId, userId int) (bool, *model.AppError) {
+	var count int64
+	if err := t.DB.Unscoped().Model(&model.Todo{}).Where("id = ? and user_id = ? and deleted_at is not null", todoId, userId).Count(&count).Error; err != nil {
+		return false, model.NewGenericInternalError(err)
+	}
+
+	return count > 0, nil
+}
+
+// RestoreTodo brings back the given todo along with the descendants deleted
+// at the same time, returning the ids of the todos restored.
+func (t *Repository) RestoreTodo(todoId, userId int) ([]int, *model.AppError) {
+	ids := []int{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM todos WHERE id = @todo AND user_id = @user
+			UNION ALL
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_todo_id = s.id
+		)
+		SELECT id FROM todos
+		WHERE deleted_at = (SELECT deleted_at FROM todos WHERE id = @todo) AND id in (SELECT id FROM subtree)`,
+		map[string]interface{}{"todo": todoId, "user": userId},
+	).Scan(&ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if err := t.DB.Unscoped().Model(&model.Todo{}).Where("id in ?", ids).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return ids, nil
 }
 
-func (t *Repository) DeleteTodo(todoId, userId int) *model.AppError {
+// RestoreTodos brings back exactly the given todos, skipping those that are
+// not deleted anymore, and returns the ids of the todos restored.
+func (t *Repository) RestoreTodos(todoIds []int, userId int) ([]int, *model.AppError) {
+	ids := []int{}
+	if err := t.DB.Unscoped().Model(&model.Todo{}).Where("id in ? and user_id = ? and deleted_at is not null", todoIds, userId).Pluck("id", &ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if err := t.DB.Unscoped().Model(&model.Todo{}).Where("id in ?", ids).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return ids, nil
+}
+
+// GetTodosUnscoped returns the given todos whether they are deleted or not.
+func (t *Repository) GetTodosUnscoped(todoIds []int, userId int) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+	if err := t.DB.Unscoped().Preload("Tags").Where("id in ? and user_id = ?", todoIds, userId).Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
+}
+
+// PurgeTodos permanently deletes the todos deleted before the given time,
+// their descendants going with them through the cascade constraint.
+func (t *Repository) PurgeTodos(before time.Time) (int, *model.AppError) {
 	var result *gorm.DB
-	if result = t.DB.Where("id = ? and user_id = ?", todoId, userId).Delete(&model.Todo{}); result.Error != nil {
-		return model.NewGenericInternalError(result.Error)
+	if result = t.DB.Unscoped().Where("deleted_at < ?", before).Delete(&model.Todo{}); result.Error != nil {
+		return 0, model.NewGenericInternalError(result.Error)
 	}
 
-	if result.RowsAffected == 0 {
-		return model.NewNotFoundError(fmt.Sprintf(model.MSG_TODO_NOT_FOUND, todoId))
+	return int(result.RowsAffected), nil
+}
+
+func (t *Repository) CheckIfTodoIsAncestor(ancestorId, todoId, userId int) (bool, *model.AppError) {
+	var count int64
+	if err := t.DB.Raw(`
+		WITH RECURSIVE ancestors(id, parent_todo_id) AS (
+			SELECT id, parent_todo_id FROM todos WHERE id = ? AND user_id = ?
+			UNION
+			SELECT t.id, t.parent_todo_id FROM todos t JOIN ancestors a ON t.id = a.parent_todo_id
+		)
+		SELECT count(*) FROM ancestors WHERE id = ?`, todoId, userId, ancestorId).Scan(&count).Error; err != nil {
+		return false, model.NewGenericInternalError(err)
 	}
 
-	return nil
+	return count > 0, nil
+}
+
+func (t *Repository) GetPendingReminders(now time.Time, limit int) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+	if err := t.DB.Preload("User").Where("remind_at <= ? and reminded_at is null and completed = ?", now, false).Where("reminder_retry_at is null or reminder_retry_at <= ?", now).Where("reminder_claimed_at is null or reminder_claimed_at <= ?", reminderClaimExpiry(now)).Order("remind_at ASC").Limit(limit).Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return todos, nil
 }
 
-func (t *Repository) DeleteManyTodos(todoIds []int, userId int) *model.AppError {
+// reminderClaimExpiry returns the time before which claims are stale, left by
+// a sender that stopped before sending the reminder or releasing it.
+func reminderClaimExpiry(now time.Time) time.Time {
+	return now.Add(-model.REMINDER_CLAIM_TIMEOUT * time.Second)
+}
+
+// ClaimReminder reserves the unsent reminder of the given todo for the caller
+// to send, reporting false if someone else holds a claim on it that has not
+// gone stale yet, so that it is not sent twice.
+func (t *Repository) ClaimReminder(todoId int, now time.Time) (bool, *model.AppError) {
 	var result *gorm.DB
-	if result = t.DB.Where("id in ? and user_id = ?", todoIds, userId).Delete(&model.Todo{}); result.Error != nil {
-		return model.NewGenericInternalError(result.Error)
+	if result = t.DB.Model(&model.Todo{}).Where("id = ? and reminded_at is null", todoId).Where("reminder_claimed_at is null or reminder_claimed_at <= ?", reminderClaimExpiry(now)).UpdateColumn("reminder_claimed_at", now); result.Error != nil {
+		return false, model.NewGenericInternalError(result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// MarkReminderSent records that the claimed reminder of the given todo was
+// delivered.
+func (t *Repository) MarkReminderSent(todoId int, remindedAt time.Time) *model.AppError {
+	if err := t.DB.Model(&model.Todo{}).Where("id = ?", todoId).UpdateColumn("reminded_at", remindedAt).Error; err != nil {
+		return model.NewGenericInternalError(err)
 	}
 
 	return nil
 }
+
+// ReleaseReminder counts a failed send of the reminder of the given todo and
+// makes it pending again from retryAt on.
+func (t *Repository) ReleaseReminder(todoId int, retryAt time.Time) *model.AppError {
+	if err := t.DB.Model(&model.Todo{}).Where("id = ?", todoId).UpdateColumns(map[string]interface{}{
+		"reminder_claimed_at": nil,
+		"reminder_attempts":   gorm.Expr("reminder_attempts + 1"),
+		"reminder_retry_at":   retryAt,
+	}).Error; err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
+
+// FailReminder counts a failed send of the reminder of the given todo and
+// marks it as done, so that it is not tried again.
+func (t *Repository) FailReminder(todoId int, now time.Time) *model.AppError {
+	if err := t.DB.Model(&model.Todo{}).Where("id = ?", todoId).UpdateColumns(map[string]interface{}{
+		"reminded_at":       now,
+		"reminder_attempts": gorm.Expr("reminder_attempts + 1"),
+		"reminder_retry_at": nil,
+	}).Error; err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
+
+func todoSiblings(parentId *int, userId int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if parentId == nil {
+			return db.Where("parent_todo_id is null and user_id = ?", userId)
+		}
+
+		return db.Where("parent_todo_id = ? and user_id = ?", *parentId, userId)
+	}
+}
+
+// GetFirstTodoPosition returns the lowest position among the children of the
+// given todo (or the root when parentId is nil), empty if there is none.
+func (t *Repository) GetFirstTodoPosition(parentId *int, userId int) (string, *model.AppError) {
+	var position string
+	if err := t.DB.Model(&model.Todo{}).Scopes(todoSiblings(parentId, userId)).Select("coalesce(min(position), '')").Scan(&position).Error; err != nil {
+		return "", model.NewGenericInternalError(err)
+	}
+
+	return position, nil
+}
+
+// GetAdjacentTodoPosition returns the position of the sibling right after (or
+// right before, when previous is true) the given position, ignoring the todo
+// being reordered. It is empty when there is no such sibling.
+func (t *Repository) GetAdjacentTodoPosition(parentId *int, userId int, position string, excludeId int, previous bool) (string, *model.AppError) {
+	query := t.DB.Model(&model.Todo{}).Scopes(todoSiblings(parentId, userId)).Where("id <> ?", excludeId)
+	if previous {
+		query = query.Where("position < ?", position).Select("coalesce(max(position), '')")
+	} else {
+		query = query.Where("position > ?", position).Select("coalesce(min(position), '')")
+	}
+
+	var adjacent string
+	if err := query.Scan(&adjacent).Error; err != nil {
+		return "", model.NewGenericInternalError(err)
+	}
+
+	return adjacent, nil
+}
+
+func (t *Repository) CheckIfTodoSiblingsUnranked(parentId *int, userId int) (bool, *model.AppError) {
+	var count int64
+	if err := t.DB.Model(&model.Todo{}).Scopes(todoSiblings(parentId, userId)).Where("position = ''").Count(&count).Error; err != nil {
+		return false, model.NewGenericInternalError(err)
+	}
+
+	return count > 0, nil
+}
+
+// RankTodoSiblings gives a position to every child of the given todo (or of
+// the root when parentId is nil), keeping the default listing order.
+func (t *Repository) RankTodoSiblings(parentId *int, userId int) *model.AppError {
+	todos := []model.Todo{}
+	if err := t.DB.Scopes(todoSiblings(parentId, userId)).Order(orderTodosBy(nil)).Find(&todos).Error; err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	ranks := util.RankSpread(len(todos))
+	for i := range todos {
+		if err := t.DB.Model(&todos[i]).UpdateColumn("position", ranks[i]).Error; err != nil {
+			return model.NewGenericInternalError(err)
+		}
+	}
+
+	return nil
+}
+
+var todoSearchTriggers = []string{"todos_fts_insert", "todos_fts_delete", "todos_fts_update"}
+
+// migrateTodoSearch creates the FTS5 index over todo descriptions and notes,
+// kept in sync by triggers so that cascading deletes are covered as well. It
+// reports false when the SQLite driver was built without FTS5, dropping the
+// triggers left by a build that had it since they would fail every write.
+func migrateTodoSearch(db *gorm.DB) (bool, error) {
+	var available bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&available).Error; err != nil {
+		return false, err
+	}
+
+	if !available {
+		for _, trigger := range todoSearchTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+				return false, err
+			}
+		}
+
+		return false, nil
+	}
+
+	var count int64
+	if err := db.Raw("SELECT count(*) FROM sqlite_master WHERE name = 'todos_fts' OR (type = 'trigger' AND name IN ?)", todoSearchTriggers).Scan(&count).Error; err != nil {
+		return false, err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(description, notes, content='todos', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos BEGIN
+			INSERT INTO todos_fts(rowid, description, notes) VALUES (new.id, new.description, new.notes);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos BEGIN
+			INSERT INTO todos_fts(todos_fts, rowid, description, notes) VALUES ('delete', old.id, old.description, old.notes);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE OF description, notes ON todos BEGIN
+			INSERT INTO todos_fts(todos_fts, rowid, description, notes) VALUES ('delete', old.id, old.description, old.notes);
+			INSERT INTO todos_fts(rowid, description, notes) VALUES (new.id, new.description, new.notes);
+		END`,
+	}
+
+	// index the todos written while the index or its triggers were missing
+	if count < int64(len(todoSearchTriggers)+1) {
+		statements = append(statements, `INSERT INTO todos_fts(todos_fts) VALUES ('rebuild')`)
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// migrateTodoVersions creates the triggers bumping the version of a todo on
+// writes that do not go through UpdateTodo, such as batch updates and tag
+// changes, so that conditional requests see every change.
+func migrateTodoVersions(db *gorm.DB) error {
+	statements := []string{
+		`CREATE TRIGGER IF NOT EXISTS todos_version_update AFTER UPDATE OF
+			parent_todo_id, description, notes, completed, auto_complete, priority, position,
+			due_at, remind_at, recurrence, occurrence, deleted_at ON todos
+			WHEN new.version = old.version BEGIN
+			UPDATE todos SET version = old.version + 1 WHERE id = new.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS todos_version_tag_insert AFTER INSERT ON todo_tags BEGIN
+			UPDATE todos SET version = version + 1 WHERE id = new.todo_id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS todos_version_tag_delete AFTER DELETE ON todo_tags BEGIN
+			UPDATE todos SET version = version + 1 WHERE id = old.todo_id;
+		END`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SearchTodos returns the todos matching every given term, best matches first.
+// Without FTS5 it falls back to a plain substring search, newest first.
+func (t *Repository) SearchTodos(terms []string, userId, limit int) ([]model.Todo, *model.AppError) {
+	todos := []model.Todo{}
+
+	if !t.FullTextSearch {
+		query := t.DB.Preload("Tags").Where("user_id = ?", userId)
+		for _, term := range terms {
+			like := "%" + likeEscaper.Replace(term) + "%"
+			query = query.Where(`(description like ? escape '\' or notes like ? escape '\')`, like, like)
+		}
+
+		if err := query.Order("updated_at DESC").Limit(limit).Find(&todos).Error; err != nil {
+			return nil, model.NewGenericInternalError(err)
+		}
+
+		return todos, nil
+	}
+
+	// quote every term so user input is never parsed as FTS5 syntax, and
+	// match them as prefixes so results show up while the user is typing
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+
+	ids := []int{}
+	if err := t.DB.Raw(`
+		SELECT todos.id FROM todos_fts JOIN todos ON todos.id = todos_fts.rowid
+		WHERE todos_fts MATCH ? AND todos.user_id = ? AND todos.deleted_at is null
+		ORDER BY bm25(todos_fts) LIMIT ?`,
+		strings.Join(quoted, " "), userId, limit,
+	).Scan(&ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if err := t.DB.Preload("Tags").Where("id in ?", ids).Find(&todos).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	ranks := make(map[int]int, len(ids))
+	for i, id := range ids {
+		ranks[id] = i
+	}
+
+	sort.Slice(todos, func(i, j int) bool { return ranks[todos[i].ID] < ranks[todos[j].ID] })
+
+	return todos, nil
+}
+
+// SetTodoDescendantsCompleted completes or reopens every descendant of the
+// given todo, returning the ids of those that were changed.
+func (t *Repository) SetTodoDescendantsCompleted(todoId, userId int, completed bool) ([]int, *model.AppError) {
+	ids := []int{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE descendants(id, completed) AS (
+			SELECT id, completed FROM todos WHERE parent_todo_id = @todo AND user_id = @user AND deleted_at is null
+			UNION ALL
+			SELECT t.id, t.completed FROM todos t JOIN descendants d ON t.parent_todo_id = d.id
+			WHERE t.user_id = @user AND t.deleted_at is null
+		)
+		SELECT id FROM descendants WHERE completed <> @completed`,
+		map[string]interface{}{"todo": todoId, "user": userId, "completed": completed},
+	).Scan(&ids).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if err := t.DB.Model(&model.Todo{}).Where("id in ?", ids).Updates(map[string]interface{}{"completed": completed, "updated_at": t.DB.NowFunc()}).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return ids, nil
+}
+
+// GetTodoProgress counts the children and descendants of each given todo,
+// along with how many of them are completed.
+func (t *Repository) GetTodoProgress(todoIds []int, userId int) (map[int]*model.TodoProgress, *model.AppError) {
+	rows := []struct {
+		TodoID                   int
+		ChildCount               int
+		CompletedChildCount      int
+		DescendantCount          int
+		CompletedDescendantCount int
+	}{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE descendants(root, id, completed, depth) AS (
+			SELECT parent_todo_id, id, completed, 1 FROM todos WHERE parent_todo_id in @todos AND user_id = @user AND deleted_at is null
+			UNION ALL
+			SELECT d.root, t.id, t.completed, d.depth + 1 FROM todos t JOIN descendants d ON t.parent_todo_id = d.id
+			WHERE t.user_id = @user AND t.deleted_at is null
+		)
+		SELECT root AS todo_id,
+			count(CASE WHEN depth = 1 THEN 1 END) AS child_count,
+			count(CASE WHEN depth = 1 AND completed THEN 1 END) AS completed_child_count,
+			count(*) AS descendant_count,
+			count(CASE WHEN completed THEN 1 END) AS completed_descendant_count
+		FROM descendants GROUP BY root`,
+		map[string]interface{}{"todos": todoIds, "user": userId},
+	).Scan(&rows).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	progress := make(map[int]*model.TodoProgress, len(todoIds))
+	for _, id := range todoIds {
+		progress[id] = model.NewTodoProgress(0, 0, 0, 0)
+	}
+
+	for _, row := range rows {
+		progress[row.TodoID] = model.NewTodoProgress(row.ChildCount, row.CompletedChildCount, row.DescendantCount, row.CompletedDescendantCount)
+	}
+
+	return progress, nil
+}
+
+// GetTodoPaths returns the ancestors of each of the given todos, ordered from
+// the root down to their parent, using a single recursive query.
+func (t *Repository) GetTodoPaths(todoIds []int, userId, depth int) (map[int][]model.Todo, *model.AppError) {
+	steps := []struct {
+		Hit   int
+		ID    int
+		Depth int
+	}{}
+	if err := t.DB.Raw(`
+		WITH RECURSIVE paths(hit, id, parent_todo_id, depth) AS (
+			SELECT id, id, parent_todo_id, 0 FROM todos WHERE id in @todos AND user_id = @user
+			UNION ALL
+			SELECT p.hit, t.id, t.parent_todo_id, p.depth + 1 FROM todos t JOIN paths p ON t.id = p.parent_todo_id
+			WHERE t.user_id = @user AND p.depth < @depth
+		)
+		SELECT hit, id, depth FROM paths WHERE depth > 0 ORDER BY hit, depth DESC`,
+		map[string]interface{}{"todos": todoIds, "user": userId, "depth": depth},
+	).Scan(&steps).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	ids := make([]int, len(steps))
+	for i, step := range steps {
+		ids[i] = step.ID
+	}
+
+	ancestors := []model.Todo{}
+	if err := t.DB.Where("id in ?", ids).Find(&ancestors).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	byId := make(map[int]model.Todo, len(ancestors))
+	for _, ancestor := range ancestors {
+		byId[ancestor.ID] = ancestor
+	}
+
+	paths := make(map[int][]model.Todo, len(todoIds))
+	for _, id := range todoIds {
+		paths[id] = make([]model.Todo, 0)
+	}
+
+	for _, step := range steps {
+		paths[step.Hit] = append(paths[step.Hit], byId[step.ID])
+	}
+
+	return paths, nil
+}
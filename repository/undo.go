@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jvitoroc/todo-go/model"
+	"gorm.io/gorm"
+)
+
+func (t *Repository) CreateUndoOperation(undo *model.UndoOperation) *model.AppError {
+	if err := t.DB.Create(undo).Error; err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
+
+func (t *Repository) GetUndoOperation(token string, userId int) (*model.UndoOperation, *model.AppError) {
+	undo := model.UndoOperation{}
+	if err := t.DB.Where("token = ? and user_id = ?", token, userId).First(&undo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.NewNotFoundError(model.MSG_UNDO_NOT_FOUND)
+		} else {
+			return nil, model.NewGenericInternalError(err)
+		}
+	}
+
+	return &undo, nil
+}
+
+// ClaimUndoOperation deletes the given operation, reporting false if it was
+// already gone so that it is never undone twice.
+func (t *Repository) ClaimUndoOperation(token string) (bool, *model.AppError) {
+	var result *gorm.DB
+	if result = t.DB.Where("token = ?", token).Delete(&model.UndoOperation{}); result.Error != nil {
+		return false, model.NewGenericInternalError(result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (t *Repository) DeleteExpiredUndoOperations(userId int, now time.Time) *model.AppError {
+	if err := t.DB.Where("user_id = ? and expires_at < ?", userId, now).Delete(&model.UndoOperation{}).Error; err != nil {
+		return model.NewGenericInternalError(err)
+	}
+
+	return nil
+}
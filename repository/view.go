@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jvitoroc/todo-go/model"
+	"gorm.io/gorm"
+)
+
+func (t *Repository) CreateView(view *model.View) (*model.View, *model.AppError) {
+	if err := t.DB.Create(view).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return view, nil
+}
+
+func (t *Repository) GetView(viewId int, userId int) (*model.View, *model.AppError) {
+	view := model.View{}
+	if err := t.DB.Where("user_id = ?", userId).First(&view, viewId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.NewNotFoundError(fmt.Sprintf(model.MSG_VIEW_NOT_FOUND, viewId))
+		} else {
+			return nil, model.NewGenericInternalError(err)
+		}
+	}
+
+	return &view, nil
+}
+
+func (t *Repository) GetViews(userId int) ([]model.View, *model.AppError) {
+	views := []model.View{}
+	if err := t.DB.Where("user_id = ?", userId).Order("name ASC").Find(&views).Error; err != nil {
+		return nil, model.NewGenericInternalError(err)
+	}
+
+	return views, nil
+}
+
+func (t *Repository) UpdateView(view *model.View) *model.AppError {
+	if err := t.DB.Save(view).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.NewNotFoundError(fmt.Sprintf(model.MSG_VIEW_NOT_FOUND, view.ID))
+		} else {
+			return model.NewGenericInternalError(err)
+		}
+	}
+
+	return nil
+}
+
+func (t *Repository) DeleteView(viewId, userId int) *model.AppError {
+	var result *gorm.DB
+	if result = t.DB.Where("id = ? and user_id = ?", viewId, userId).Delete(&model.View{}); result.Error != nil {
+		return model.NewGenericInternalError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewNotFoundError(fmt.Sprintf(model.MSG_VIEW_NOT_FOUND, viewId))
+	}
+
+	return nil
+}
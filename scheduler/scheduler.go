@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/jvitoroc/todo-go/app"
+	"github.com/jvitoroc/todo-go/config"
+	"github.com/jvitoroc/todo-go/model"
+)
+
+type Clock func() time.Time
+
+// loop calls a tick function at a fixed interval until it is stopped.
+type loop struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (l *loop) start(interval time.Duration, tick func()) {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go l.run(interval, tick)
+}
+
+// Stop signals the scheduler to exit and waits for the current tick, if any,
+// to finish.
+func (l *loop) Stop() {
+	close(l.stop)
+	<-l.done
+}
+
+func (l *loop) run(interval time.Duration, tick func()) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tick()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+type ReminderScheduler struct {
+	loop
+
+	App      *app.App
+	Interval time.Duration
+	Now      Clock
+}
+
+func NewReminderScheduler(app *app.App, cfg *config.Config) *ReminderScheduler {
+	interval := cfg.Reminder.Interval
+	if interval <= 0 {
+		interval = model.REMINDER_INTERVAL
+	}
+
+	return &ReminderScheduler{
+		App:      app,
+		Interval: time.Duration(interval) * time.Second,
+		Now:      time.Now,
+	}
+}
+
+func (s *ReminderScheduler) Start() {
+	s.start(s.Interval, s.Tick)
+}
+
+func (s *ReminderScheduler) Tick() {
+	if err := s.App.SendDueReminders(s.Now()); err != nil {
+		log.Printf("Could not send reminders: %s (%s)", err.Message, err.Detail)
+	}
+}
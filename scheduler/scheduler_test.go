@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jvitoroc/todo-go/app"
+	"github.com/jvitoroc/todo-go/config"
+	"github.com/jvitoroc/todo-go/model"
+	"github.com/jvitoroc/todo-go/repository"
+)
+
+// fakeSender records the emails it is asked to send, failing while fail is set.
+type fakeSender struct {
+	sent []string
+	fail bool
+}
+
+func (s *fakeSender) SendEmail(to, subject, body string) error {
+	if s.fail {
+		return errors.New("mailbox unavailable")
+	}
+
+	s.sent = append(s.sent, to)
+	return nil
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// newTestScheduler starts a scheduler as the server would, on the database
+// left by the previous one, if any.
+func newTestScheduler(t *testing.T, sender *fakeSender, clock *fakeClock) *ReminderScheduler {
+	cfg := &config.Config{}
+	repo := repository.NewRepository(cfg)
+	t.Cleanup(func() {
+		if db, err := repo.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+
+	s := NewReminderScheduler(app.NewApp(repo, sender, nil, cfg), cfg)
+	s.Now = clock.Now
+
+	return s
+}
+
+// cleanDatabase starts the test on an empty database, removed again once the
+// test is done.
+func cleanDatabase(t *testing.T) {
+	os.Remove("test.db")
+	t.Cleanup(func() { os.Remove("test.db") })
+}
+
+// createReminder creates a user with a todo whose reminder is due a minute
+// before the given time.
+func createReminder(t *testing.T, s *ReminderScheduler, now time.Time) *model.Todo {
+	t.Helper()
+
+	user := &model.User{Username: "reminded", Email: "reminded@example.com", Password: "secret", Verified: true}
+	if err := s.App.Repository.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	remindAt := now.Add(-time.Minute)
+	todo := &model.Todo{UserID: user.ID, Description: "Water the plants", RemindAt: &remindAt}
+	if err := s.App.CreateTodo(todo); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	return todo
+}
+
+func getTodo(t *testing.T, s *ReminderScheduler, todoId int) *model.Todo {
+	t.Helper()
+	todo := &model.Todo{}
+	if err := s.App.Repository.DB.First(todo, todoId).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return todo
+}
+
+func TestReminderSentOnce(t *testing.T) {
+	cleanDatabase(t)
+	sender := &fakeSender{}
+	clock := &fakeClock{now: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, sender, clock)
+	createReminder(t, s, clock.now)
+
+	s.Tick()
+	clock.now = clock.now.Add(time.Minute)
+	s.Tick()
+
+	if len(sender.sent) != 1 || sender.sent[0] != "reminded@example.com" {
+		t.Fatalf("expected a single reminder, sent %v", sender.sent)
+	}
+}
+
+func TestStaleClaimSentOnceAfterRestart(t *testing.T) {
+	cleanDatabase(t)
+	sender := &fakeSender{}
+	clock := &fakeClock{now: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, sender, clock)
+	todo := createReminder(t, s, clock.now)
+
+	// claimed right before a crash, so it was never sent
+	if ok, err := s.App.Repository.ClaimReminder(todo.ID, clock.now); err != nil || !ok {
+		t.Fatalf("could not claim the reminder: %v", err)
+	}
+
+	restarted := newTestScheduler(t, sender, clock)
+	clock.now = clock.now.Add(time.Minute)
+	restarted.Tick()
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected a fresh claim not to be sent again, sent %v", sender.sent)
+	}
+
+	clock.now = clock.now.Add(model.REMINDER_CLAIM_TIMEOUT * time.Second)
+	restarted.Tick()
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the stale claim to be sent, sent %v", sender.sent)
+	}
+
+	clock.now = clock.now.Add(model.REMINDER_CLAIM_TIMEOUT * time.Second)
+	restarted.Tick()
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the reminder to be sent once, sent %v", sender.sent)
+	}
+}
+
+func TestFailedReminderReleased(t *testing.T) {
+	cleanDatabase(t)
+	sender := &fakeSender{fail: true}
+	clock := &fakeClock{now: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, sender, clock)
+	todo := createReminder(t, s, clock.now)
+
+	s.Tick()
+
+	released := getTodo(t, s, todo.ID)
+	if released.RemindedAt != nil || released.ReminderClaimedAt != nil || released.ReminderAttempts != 1 {
+		t.Fatalf("expected the reminder to be released after one attempt, got remindedAt %v, claimed %v and %d attempts", released.RemindedAt, released.ReminderClaimedAt, released.ReminderAttempts)
+	}
+
+	// not retried before its backoff is over
+	sender.fail = false
+	s.Tick()
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected no retry before the backoff, sent %v", sender.sent)
+	}
+
+	clock.now = clock.now.Add(model.REMINDER_RETRY_DELAY * time.Second)
+	s.Tick()
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the reminder to be retried, sent %v", sender.sent)
+	}
+}
+
+func TestFailingReminderGivenUp(t *testing.T) {
+	cleanDatabase(t)
+	sender := &fakeSender{fail: true}
+	clock := &fakeClock{now: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, sender, clock)
+	todo := createReminder(t, s, clock.now)
+
+	for i := 0; i < model.REMINDER_MAX_ATTEMPTS+2; i++ {
+		s.Tick()
+		clock.now = clock.now.Add(24 * time.Hour)
+	}
+
+	failed := getTodo(t, s, todo.ID)
+	if failed.RemindedAt == nil || failed.ReminderAttempts != model.REMINDER_MAX_ATTEMPTS {
+		t.Fatalf("expected the reminder to be given up after %d attempts, got remindedAt %v and %d attempts", model.REMINDER_MAX_ATTEMPTS, failed.RemindedAt, failed.ReminderAttempts)
+	}
+}
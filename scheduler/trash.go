@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/jvitoroc/todo-go/app"
+	"github.com/jvitoroc/todo-go/config"
+	"github.com/jvitoroc/todo-go/model"
+)
+
+// TrashScheduler periodically purges the todos kept in the trash for longer
+// than the configured retention.
+type TrashScheduler struct {
+	loop
+
+	App      *app.App
+	Interval time.Duration
+	Now      Clock
+}
+
+func NewTrashScheduler(app *app.App, cfg *config.Config) *TrashScheduler {
+	interval := cfg.Trash.Interval
+	if interval <= 0 {
+		interval = model.TRASH_PURGE_INTERVAL
+	}
+
+	return &TrashScheduler{
+		App:      app,
+		Interval: time.Duration(interval) * time.Second,
+		Now:      time.Now,
+	}
+}
+
+func (s *TrashScheduler) Start() {
+	s.start(s.Interval, s.Tick)
+}
+
+func (s *TrashScheduler) Tick() {
+	if err := s.App.PurgeTrash(s.Now()); err != nil {
+		log.Printf("Could not purge the trash: %s (%s)", err.Message, err.Detail)
+	}
+}
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jvitoroc/todo-go/api"
@@ -11,13 +16,18 @@ import (
 	"github.com/jvitoroc/todo-go/config"
 	"github.com/jvitoroc/todo-go/email"
 	"github.com/jvitoroc/todo-go/repository"
+	"github.com/jvitoroc/todo-go/scheduler"
 	"github.com/rs/cors"
 )
 
+const SHUTDOWN_TIMEOUT = 10 // maximum time in seconds to wait for in-flight requests on shutdown
+
 type Server struct {
-	API    *api.API
-	Config *config.Config
-	Router *mux.Router
+	API               *api.API
+	Config            *config.Config
+	Router            *mux.Router
+	ReminderScheduler *scheduler.ReminderScheduler
+	TrashScheduler    *scheduler.TrashScheduler
 }
 
 func NewServer() *Server {
@@ -29,11 +39,15 @@ func NewServer() *Server {
 
 	app := app.NewApp(repo, email, auth, cfg)
 	api := api.NewAPI(app, router)
+	reminderScheduler := scheduler.NewReminderScheduler(app, cfg)
+	trashScheduler := scheduler.NewTrashScheduler(app, cfg)
 
 	return &Server{
-		API:    api,
-		Config: cfg,
-		Router: router,
+		API:               api,
+		Config:            cfg,
+		Router:            router,
+		ReminderScheduler: reminderScheduler,
+		TrashScheduler:    trashScheduler,
 	}
 }
 
@@ -47,8 +61,31 @@ func (s *Server) Start() {
 	addr := ":" + s.Config.Server.Port
 	s.Router.Use(setBasicsMiddleware)
 
-	if err := http.ListenAndServe(addr, c.Handler(s.Router)); err != nil {
-		log.Fatalf("Could not start the server: %s", err.Error())
+	srv := &http.Server{Addr: addr, Handler: c.Handler(s.Router)}
+	// event streams never finish on their own, so end them when shutting down
+	srv.RegisterOnShutdown(s.API.App.Events.Close)
+
+	s.ReminderScheduler.Start()
+	defer s.ReminderScheduler.Stop()
+
+	s.TrashScheduler.Start()
+	defer s.TrashScheduler.Stop()
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Could not start the server: %s", err.Error())
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Could not shut down the server gracefully: %s", err.Error())
 	}
 }
 
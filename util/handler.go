@@ -23,10 +23,19 @@ func ExtractParamInt(param string, r *http.Request) (int, bool) {
 }
 
 func ExtractFormValue(param string, r *http.Request) (string, bool) {
-	value := r.FormValue("parents-count")
+	value := r.FormValue(param)
 	return value, value != ""
 }
 
+func ExtractFormValues(param string, r *http.Request) ([]string, bool) {
+	if err := r.ParseForm(); err != nil {
+		return nil, false
+	}
+
+	values := r.Form[param]
+	return values, len(values) > 0
+}
+
 func ExtractFormInt(param string, r *http.Request) (int, bool) {
 	if value, ok := ExtractFormValue(param, r); ok {
 		if value, err := strconv.Atoi(value); err == nil {
@@ -36,3 +45,13 @@ func ExtractFormInt(param string, r *http.Request) (int, bool) {
 
 	return 0, false
 }
+
+func ExtractFormBool(param string, r *http.Request) (bool, bool) {
+	if value, ok := ExtractFormValue(param, r); ok {
+		if value, err := strconv.ParseBool(value); err == nil {
+			return value, true
+		}
+	}
+
+	return false, false
+}
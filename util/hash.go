@@ -1,6 +1,9 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,3 +23,10 @@ func VerifyPasswordHash(passwordHash, password string) bool {
 
 	return true
 }
+
+// Digest returns a short URL safe digest of the given data, meant to tell
+// payloads apart rather than to protect secrets.
+func Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,66 @@
+package util
+
+import "strings"
+
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RankBetween returns a key that sorts strictly between before and after,
+// where an empty before means the start and an empty after means the end.
+// Keys never end in the lowest digit, so there is always room in between.
+// An after key not greater than before is ignored rather than looping forever.
+func RankBetween(before, after string) string {
+	if after != "" && before >= after {
+		after = ""
+	}
+
+	rank := []byte{}
+	bounded := after != ""
+
+	for i := 0; ; i++ {
+		low := 0
+		if i < len(before) {
+			low = strings.IndexByte(rankDigits, before[i])
+		}
+
+		high := len(rankDigits)
+		if bounded {
+			high = 0
+			if i < len(after) {
+				high = strings.IndexByte(rankDigits, after[i])
+			}
+		}
+
+		if high-low > 1 {
+			return string(append(rank, rankDigits[(low+high)/2]))
+		}
+
+		// no room at this digit, keep the lower one and look further down
+		rank = append(rank, rankDigits[low])
+		if high > low {
+			bounded = false
+		}
+	}
+}
+
+// RankSpread returns count keys in ascending order, evenly spread so that
+// later insertions between any of them stay short.
+func RankSpread(count int) []string {
+	base := len(rankDigits)
+	width, space := 1, base
+	for space <= count {
+		width, space = width+1, space*base
+	}
+
+	ranks := make([]string, count)
+	for i := range ranks {
+		value := (i + 1) * space / (count + 1)
+		rank := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			rank[j] = rankDigits[value%base]
+			value /= base
+		}
+		ranks[i] = strings.TrimRight(string(rank), rankDigits[:1])
+	}
+
+	return ranks
+}